@@ -0,0 +1,44 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewFromClient wraps existing client, it is not disconnected on Close
+func NewFromClient(client *mongo.Client, dbName string, optFn ...OptionFn) IClient {
+	return NewFromDatabase(client.Database(dbName), optFn...)
+}
+
+// NewFromDatabase wraps existing database handle, it's client is not disconnected on Close
+func NewFromDatabase(db *mongo.Database, optFn ...OptionFn) IClient {
+	w := newWrapper(false, optFn...)
+	w.setDatabase(db)
+	return w
+}
+
+func newWrapper(ownClient bool, optFn ...OptionFn) *dbWrapper {
+	w := &dbWrapper{
+		ownClient: ownClient,
+		txRetry:   txRetry{maxDuration: defaultTxMaxDuration, backoff: defaultTxBackoff},
+		logger:    logger.NewGlobalZerolog(),
+	}
+	for _, opt := range optFn {
+		opt(w)
+	}
+	return w
+}
+
+// setDatabase binds client to database and detects topology if it's not set
+func (w *dbWrapper) setDatabase(db *mongo.Database) {
+	w.db = db
+	if w.topology == TopologyUnknown {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+		var err error
+		if w.topology, err = detectTopology(ctx, db.Client()); err != nil {
+			w.logger.Warn("can't detect topology", "error", err)
+		}
+	}
+}
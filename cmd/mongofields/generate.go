@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+const (
+	fieldCollection = "collection"
+	fieldPath       = "Path"
+)
+
+// field is a model's field with bson path, nested document has children
+type field struct {
+	goName   string
+	path     string
+	typeName string
+	children []*field
+}
+
+// Generate returns source of field names of models in package dir, all models with collection field are used
+// when types are empty
+func Generate(dir string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	models, err := findModels(pkg, typeNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, errors.New("no models found")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by mongofields; DO NOT EDIT.\n\npackage %s\n", pkg.Name())
+	for _, tn := range models {
+		varName := tn.Name() + "Fields"
+		typeName := lowerFirst(tn.Name()) + "Fields"
+		if typeName == varName {
+			typeName += "Type"
+		}
+
+		root := &field{typeName: typeName}
+		root.children, err = structFields(tn.Type().Underlying().(*types.Struct), "", lowerFirst(tn.Name()), map[*types.Struct]bool{})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", tn.Name(), err)
+		}
+
+		fmt.Fprintf(&buf, "\n// %s are bson field names of %s\nvar %s = ", varName, tn.Name(), varName)
+		writeValue(&buf, root)
+		buf.WriteString("\n")
+		writeTypes(&buf, root, true)
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func loadPackage(dir string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	// errors are ignored, e.g. stale generated code, since only struct declarations are needed
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil), Error: func(error) {}}
+	pkg, _ := conf.Check(bp.ImportPath, fset, files, nil)
+	if pkg == nil {
+		return nil, fmt.Errorf("can't load package %s", dir)
+	}
+	return pkg, nil
+}
+
+func findModels(pkg *types.Package, typeNames []string) ([]*types.TypeName, error) {
+	var res []*types.TypeName
+	if len(typeNames) > 0 {
+		for _, name := range typeNames {
+			tn, ok := pkg.Scope().Lookup(strings.TrimSpace(name)).(*types.TypeName)
+			if !ok {
+				return nil, fmt.Errorf("type %s not found", name)
+			}
+			if _, ok := tn.Type().Underlying().(*types.Struct); !ok {
+				return nil, fmt.Errorf("type %s is not a struct", name)
+			}
+			res = append(res, tn)
+		}
+		return res, nil
+	}
+
+	for _, name := range pkg.Scope().Names() {
+		tn, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		st, ok := tn.Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i).Name() == fieldCollection {
+				res = append(res, tn)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// structFields returns fields like model.Fields does, inline structs are flattened,
+// visiting has structs of enclosing documents to stop on recursive types
+func structFields(st *types.Struct, prefix, typePrefix string, visiting map[*types.Struct]bool) ([]*field, error) {
+	visiting[st] = true
+	defer delete(visiting, st)
+
+	var res []*field
+	seen := map[string]bool{}
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if f.Name() == fieldCollection && prefix == "" {
+			continue
+		}
+
+		tag := reflect.StructTag(st.Tag(i)).Get("bson")
+		if tag == "-" {
+			continue
+		}
+		name, flags := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, flags = tag[:i], tag[i+1:]
+		}
+
+		ft := f.Type()
+		if p, ok := ft.(*types.Pointer); ok {
+			ft = p.Elem()
+		}
+		// like driver, embedded structs without inline flag are nested documents
+		if sub, ok := ft.Underlying().(*types.Struct); ok && strings.Contains(","+flags+",", ",inline,") {
+			if visiting[sub] {
+				continue
+			}
+			children, err := structFields(sub, prefix, typePrefix, visiting)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range children {
+				if !seen[c.goName] {
+					seen[c.goName] = true
+					res = append(res, c)
+				}
+			}
+			continue
+		}
+
+		if !f.Exported() || seen[f.Name()] {
+			continue
+		}
+		seen[f.Name()] = true
+		if name == "" {
+			name = strings.ToLower(f.Name())
+		}
+
+		node := &field{goName: f.Name(), path: prefix + name}
+		if sub, ok := documentStruct(ft); ok && !visiting[sub] {
+			node.typeName = typePrefix + f.Name() + "Fields"
+			children, err := structFields(sub, node.path+".", typePrefix+f.Name(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range children {
+				if c.goName == fieldPath {
+					return nil, fmt.Errorf("field %s.%s conflicts with document path", f.Name(), fieldPath)
+				}
+			}
+			node.children = children
+		}
+		res = append(res, node)
+	}
+	return res, nil
+}
+
+// documentStruct returns struct stored as nested document with queryable fields
+func documentStruct(t types.Type) (*types.Struct, bool) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, false
+	}
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return nil, false
+		}
+		// GeoJSON geometries are queried as a whole
+		if m, _, _ := types.LookupFieldOrMethod(types.NewPointer(named), true, obj.Pkg(), "GeoJSONType"); m != nil {
+			return nil, false
+		}
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Exported() {
+			return st, true
+		}
+	}
+	return nil, false
+}
+
+func writeValue(buf *bytes.Buffer, f *field) {
+	fmt.Fprintf(buf, "%s{\n", f.typeName)
+	if f.path != "" {
+		fmt.Fprintf(buf, "%s: %q,\n", fieldPath, f.path)
+	}
+	for _, c := range f.children {
+		if c.children == nil {
+			fmt.Fprintf(buf, "%s: %q,\n", c.goName, c.path)
+			continue
+		}
+		fmt.Fprintf(buf, "%s: ", c.goName)
+		writeValue(buf, c)
+		buf.WriteString(",\n")
+	}
+	buf.WriteString("}")
+}
+
+func writeTypes(buf *bytes.Buffer, f *field, root bool) {
+	if !root {
+		fmt.Fprintf(buf, "\n// %s are bson field names of %s document, %s is a name of document itself\n", f.typeName, f.path, fieldPath)
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", f.typeName)
+	if !root {
+		fmt.Fprintf(buf, "%s string\n", fieldPath)
+	}
+	for _, c := range f.children {
+		if c.children == nil {
+			fmt.Fprintf(buf, "%s string\n", c.goName)
+		} else {
+			fmt.Fprintf(buf, "%s %s\n", c.goName, c.typeName)
+		}
+	}
+	buf.WriteString("}\n")
+
+	for _, c := range f.children {
+		if c.children != nil {
+			writeTypes(buf, c, false)
+		}
+	}
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
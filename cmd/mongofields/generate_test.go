@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	expected, err := os.ReadFile("testdata/books/books_fields.golden")
+	require.NoError(t, err)
+
+	src, err := Generate("testdata/books", nil)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(src))
+}
+
+func TestGenerate_Types(t *testing.T) {
+	src, err := Generate("testdata/books", []string{"review"})
+	require.NoError(t, err)
+	require.Contains(t, string(src), "var reviewFields = reviewFieldsType{")
+	require.NotContains(t, string(src), "BookFields")
+
+	_, err = Generate("testdata/books", []string{"Magazine"})
+	require.EqualError(t, err, "type Magazine not found")
+}
@@ -0,0 +1,54 @@
+// Command mongofields generates bson field names of models, e.g. for model Book it generates
+// BookFields.Name == "name", BookFields.CreatedAt == "createdAt" and BookFields.Address.City == "address.city".
+//
+// Usage in package with models:
+//
+//	//go:generate go run github.com/sanches1984/gopkg-mongo-orm/cmd/mongofields -type Book,Author
+//
+// Without -type all structs with `collection` field are used.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("mongofields: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of model names, default is all models with collection field")
+	output := flag.String("output", "", "output file name, default is <file>_fields.go")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	src, err := Generate(dir, types)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := *output
+	if name == "" {
+		name = "fields_gen.go"
+		if file := os.Getenv("GOFILE"); file != "" {
+			name = strings.TrimSuffix(file, ".go") + "_fields.go"
+		}
+		name = filepath.Join(dir, name)
+	}
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		log.Fatal(fmt.Errorf("write output: %w", err))
+	}
+}
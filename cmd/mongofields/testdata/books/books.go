@@ -0,0 +1,49 @@
+package books
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"time"
+)
+
+type Book struct {
+	collection         struct{} `bson:"books"`
+	model.DefaultModel `bson:",inline"`
+	Name               string      `bson:"name"`
+	Pages              int         `bson:"pages,omitempty"`
+	Author             Author      `bson:"author"`
+	Location           model.Point `bson:"location"`
+	PublishedAt        *time.Time  `bson:"publishedAt"`
+	Secret             string      `bson:"-"`
+	Rating             float64
+	internal           string
+}
+
+type Author struct {
+	Name    string `bson:"name"`
+	Address struct {
+		City string `bson:"city"`
+	} `bson:"address"`
+}
+
+type review struct {
+	collection    struct{} `bson:"reviews"`
+	model.IDField `bson:",inline"`
+	Text          string `bson:"text"`
+}
+
+type Article struct {
+	collection    struct{} `bson:"articles"`
+	model.IDField `bson:",inline"`
+	model.DateFields
+	Title string `bson:"title"`
+}
+
+type Category struct {
+	collection struct{}  `bson:"categories"`
+	Name       string    `bson:"name"`
+	Parent     *Category `bson:"parent"`
+	Owner      struct {
+		Name     string    `bson:"name"`
+		Category *Category `bson:"category"`
+	} `bson:"owner"`
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
@@ -22,9 +23,13 @@ type Config struct {
 	Username   string
 	Password   string
 	Timeout    time.Duration
+	// SlowQuery is a threshold for logging slow commands, zero disables log
+	SlowQuery time.Duration
+	// OnQuery is called for every finished command
+	OnQuery func(QueryEvent)
 }
 
-func Connect(appName string, config *Config) (IClient, error) {
+func Connect(appName string, config *Config, optFn ...OptionFn) (IClient, error) {
 	if config == nil {
 		return nil, errors.New("config not set")
 	}
@@ -43,12 +48,28 @@ func Connect(appName string, config *Config) (IClient, error) {
 			}))
 	}
 
-	db, err := connect(context.TODO(), config)
+	w := newWrapper(true, optFn...)
+	clientOpts := w.clientOpts
+	if mon := newQueryMonitor(config); mon != nil {
+		mon.logger = w.logger
+		// options are merged last wins, so user's monitor is called by query monitor
+		var userMonitor *event.CommandMonitor
+		for _, o := range clientOpts {
+			if o != nil && o.Monitor != nil {
+				userMonitor = o.Monitor
+			}
+		}
+		clientOpts = append(clientOpts, options.Client().SetMonitor(chainMonitors(userMonitor, mon.commandMonitor())))
+	}
+
+	db, err := connect(context.TODO(), config, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &dbWrapper{db: db, hasRS: config.ReplicaSet != ""}, nil
+	w.setDatabase(db)
+	w.logger.Info("connected to database", "database", db.Name(), "topology", w.topology.String())
+	return w, nil
 }
 
 func ParseURL(dsn string) (*Config, error) {
@@ -73,9 +94,10 @@ func ParseURL(dsn string) (*Config, error) {
 		Timeout:    defaultTimeout,
 	}, nil
 }
-func connect(ctx context.Context, config *Config) (*mongo.Database, error) {
-	opts := options.Client().ApplyURI(config.DSN)
-	client, err := mongo.NewClient(opts)
+
+func connect(ctx context.Context, config *Config, clientOpts ...*options.ClientOptions) (*mongo.Database, error) {
+	opts := append([]*options.ClientOptions{options.Client().ApplyURI(config.DSN)}, clientOpts...)
+	client, err := mongo.NewClient(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1,7 +1,14 @@
 package mongodb
 
 import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"testing"
 )
 
@@ -30,3 +37,111 @@ func TestParseURL(t *testing.T) {
 		require.Equal(t, c.config, cfg)
 	}
 }
+
+func TestGetCollectionName(t *testing.T) {
+	type archiveItem struct {
+		collection         struct{} `bson:"books" database:"archive"`
+		model.DefaultModel `bson:",inline"`
+	}
+
+	type plainItem struct {
+		collection         struct{} `bson:"books"`
+		model.DefaultModel `bson:",inline"`
+	}
+
+	coll, db, err := getCollectionName(&archiveItem{})
+	require.NoError(t, err)
+	require.Equal(t, "books", coll)
+	require.Equal(t, "archive", db)
+
+	coll, db, err = getCollectionName(&plainItem{})
+	require.NoError(t, err)
+	require.Equal(t, "books", coll)
+	require.Empty(t, db)
+
+	_, _, err = getCollectionName(&model.DefaultModel{})
+	require.ErrorIs(t, err, errIncorrectModelInterface)
+
+	coll, db, err = CollectionName(&[]*archiveItem{})
+	require.NoError(t, err)
+	require.Equal(t, "books", coll)
+	require.Equal(t, "archive", db)
+}
+
+func TestNewFromClient(t *testing.T) {
+	mc, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err)
+
+	client := NewFromClient(mc, "test", WithTopology(TopologySharded))
+	require.Equal(t, "test", client.DB().Name())
+	require.Equal(t, TopologySharded, client.Topology())
+	require.True(t, client.(*dbWrapper).supportsTransactions())
+	require.Equal(t, "other", client.WithDatabase("other").DB().Name())
+	require.NoError(t, client.Close())
+
+	owner := &dbWrapper{db: mc.Database("test"), ownClient: true}
+	require.False(t, owner.WithDatabase("other").(*dbWrapper).ownClient)
+}
+
+func TestWithLogger(t *testing.T) {
+	require.Equal(t, logger.NewGlobalZerolog(), newWrapper(false).logger)
+	require.Equal(t, logger.Nop(), newWrapper(false, WithLogger(nil)).logger)
+}
+
+func TestCount_Near(t *testing.T) {
+	mc, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err)
+	client := NewFromClient(mc, "test")
+
+	_, err = client.Count(context.Background(), &placeItem{}, opt.List(
+		opt.Eq("name", "cafe"),
+		opt.Or(opt.NearSphere("location", model.NewPoint(2.35, 48.85), 1000, 0), opt.Eq("owner", "bob")),
+	))
+	require.Equal(t, errCountNear, err)
+	require.False(t, hasOperator(bson.M{"location": bson.M{"$geoWithin": bson.M{}}}, "$near", "$nearSphere"))
+}
+
+func TestValidate_Negated(t *testing.T) {
+	w := &dbWrapper{}
+	cases := []struct {
+		opt opt.FnOpt
+		err string
+	}{
+		{opt: opt.Not(opt.Text("coffee", "", false)), err: "$text can't be negated with $not or $nor"},
+		{opt: opt.Not(opt.Near("location", model.NewPoint(2.35, 48.85), 1000, 0)), err: "$near can't be negated with $not or $nor"},
+		{opt: opt.Not(opt.Eq("name", "cafe"), opt.NearSphere("location", model.NewPoint(2.35, 48.85), 1000, 0)), err: "$nearSphere can't be negated with $not or $nor"},
+	}
+	for _, c := range cases {
+		require.EqualError(t, w.validate(&placeItem{}, opt.List(c.opt)), c.err)
+	}
+
+	require.NoError(t, w.validate(&placeItem{}, opt.List(
+		opt.Text("coffee", "", false),
+		opt.Not(opt.Eq("name", "cafe")),
+		opt.Near("location", model.NewPoint(2.35, 48.85), 1000, 0),
+	)))
+}
+
+func TestTopology(t *testing.T) {
+	cases := []struct {
+		hello    helloResult
+		topology Topology
+	}{
+		{hello: helloResult{}, topology: TopologyStandalone},
+		{hello: helloResult{SetName: "rs0"}, topology: TopologyReplicaSet},
+		{hello: helloResult{Msg: "isdbgrid"}, topology: TopologySharded},
+	}
+
+	for _, c := range cases {
+		require.Equal(t, c.topology, c.hello.topology())
+	}
+
+	require.False(t, TopologyStandalone.SupportsTransactions())
+	require.False(t, TopologyUnknown.SupportsTransactions())
+	require.True(t, TopologyReplicaSet.SupportsTransactions())
+	require.True(t, TopologySharded.SupportsTransactions())
+
+	w := &dbWrapper{topology: TopologyStandalone}
+	WithTransactions(true)(w)
+	require.True(t, w.supportsTransactions())
+}
@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"regexp"
+)
+
+const codeDocumentValidationFailure = 121
+
+var (
+	ErrNotFound     = errors.New("document not found")
+	ErrDuplicateKey = errors.New("duplicate key")
+	ErrTimeout      = errors.New("operation timeout")
+	ErrNetwork      = errors.New("network error")
+	ErrValidation   = errors.New("document validation failed")
+)
+
+// Error is a driver error of known kind, errors.Is matches it with kind sentinel
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// DuplicateKeyError is a unique index violation, errors.Is matches it with ErrDuplicateKey
+type DuplicateKeyError struct {
+	// Index is a name of violated index
+	Index string
+	// Key is a duplicated key as server prints it, e.g. `{ name: "hello" }`
+	Key string
+	Err error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	if e.Index == "" {
+		return ErrDuplicateKey.Error() + ": " + e.Err.Error()
+	}
+	return ErrDuplicateKey.Error() + " in index " + e.Index + ": " + e.Key
+}
+
+func (e *DuplicateKeyError) Is(target error) bool {
+	return target == ErrDuplicateKey
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.Err
+}
+
+var duplicateKeyRe = regexp.MustCompile(`index: (\S+)(?: dup key: (\{.*\}))?`)
+
+// ClassifyError wraps driver error into Error or DuplicateKeyError, other errors are returned as is
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	var dupErr *DuplicateKeyError
+	if errors.As(err, &e) || errors.As(err, &dupErr) {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &Error{Kind: ErrNotFound, Err: err}
+	case mongo.IsDuplicateKeyError(err):
+		return newDuplicateKeyError(err)
+	case mongo.IsTimeout(err), errors.Is(err, context.DeadlineExceeded), hasErrorCode(err, codeMaxTimeMSExpired):
+		return &Error{Kind: ErrTimeout, Err: err}
+	case mongo.IsNetworkError(err):
+		return &Error{Kind: ErrNetwork, Err: err}
+	case hasErrorCode(err, codeDocumentValidationFailure):
+		return &Error{Kind: ErrValidation, Err: err}
+	}
+	return err
+}
+
+func newDuplicateKeyError(err error) *DuplicateKeyError {
+	res := &DuplicateKeyError{Err: err}
+	msg := err.Error()
+
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == 11000 || e.Code == 11001 || e.Code == 12582 {
+				msg = e.Message
+				break
+			}
+		}
+	}
+
+	if m := duplicateKeyRe.FindStringSubmatch(msg); m != nil {
+		res.Index = m[1]
+		res.Key = m[2]
+	}
+	return res
+}
+
+func hasErrorCode(err error, code int) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(code)
+}
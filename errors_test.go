@@ -0,0 +1,51 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	dupErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    11000,
+		Message: `E11000 duplicate key error collection: test.books index: name_1 dup key: { name: "hello" }`,
+	}}}
+	validationErr := mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    codeDocumentValidationFailure,
+		Message: "Document failed validation",
+	}}}
+
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{err: mongo.ErrNoDocuments, kind: ErrNotFound},
+		{err: fmt.Errorf("find: %w", mongo.ErrNoDocuments), kind: ErrNotFound},
+		{err: dupErr, kind: ErrDuplicateKey},
+		{err: context.DeadlineExceeded, kind: ErrTimeout},
+		{err: mongo.CommandError{Code: codeMaxTimeMSExpired, Name: "MaxTimeMSExpired"}, kind: ErrTimeout},
+		{err: mongo.CommandError{Code: 6, Name: "HostUnreachable", Labels: []string{"NetworkError"}}, kind: ErrNetwork},
+		{err: validationErr, kind: ErrValidation},
+	}
+
+	for _, c := range cases {
+		err := ClassifyError(c.err)
+		require.ErrorIs(t, err, c.kind)
+		require.Equal(t, c.err, errors.Unwrap(err))
+		require.Equal(t, err, ClassifyError(err))
+	}
+
+	var dup *DuplicateKeyError
+	require.ErrorAs(t, ClassifyError(dupErr), &dup)
+	require.Equal(t, "name_1", dup.Index)
+	require.Equal(t, `{ name: "hello" }`, dup.Key)
+	require.EqualError(t, dup, `duplicate key in index name_1: { name: "hello" }`)
+
+	other := errors.New("other")
+	require.Equal(t, other, ClassifyError(other))
+	require.NoError(t, ClassifyError(nil))
+}
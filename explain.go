@@ -0,0 +1,253 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// ExplainVerbosity is a mode of explain command
+type ExplainVerbosity string
+
+const (
+	// ExplainQueryPlanner returns winning plan without running query
+	ExplainQueryPlanner ExplainVerbosity = "queryPlanner"
+	// ExplainExecutionStats runs query and returns execution stats of winning plan
+	ExplainExecutionStats ExplainVerbosity = "executionStats"
+	// ExplainAllPlansExecution runs query and returns execution stats of all candidate plans
+	ExplainAllPlansExecution ExplainVerbosity = "allPlansExecution"
+)
+
+// stages of query plan
+const (
+	StageCollScan = "COLLSCAN"
+	StageIxScan   = "IXSCAN"
+)
+
+// ErrCollScan is returned by collection scan guard in fail mode
+var ErrCollScan = errors.New("collection scan")
+
+// Plan is a parsed explain output of Find query
+type Plan struct {
+	// Stage is a root stage of winning plan, e.g. FETCH
+	Stage string
+	// Stages are all stages of winning plan from root to leaves
+	Stages []string
+	// Indexes are names of indexes scanned by winning plan, empty for collection scan
+	Indexes []string
+	// KeysExamined, DocsExamined, Returned and ExecutionTime are set with execution stats verbosity
+	KeysExamined  int64
+	DocsExamined  int64
+	Returned      int64
+	ExecutionTime time.Duration
+	// Raw is a server's explain output
+	Raw bson.Raw
+}
+
+// IsCollScan responds whether winning plan scans whole collection
+func (p *Plan) IsCollScan() bool {
+	for _, stage := range p.Stages {
+		if stage == StageCollScan {
+			return true
+		}
+	}
+	return false
+}
+
+type explainOutput struct {
+	QueryPlanner struct {
+		WinningPlan planStage `bson:"winningPlan"`
+	} `bson:"queryPlanner"`
+	ExecutionStats struct {
+		NReturned           int64 `bson:"nReturned"`
+		ExecutionTimeMillis int64 `bson:"executionTimeMillis"`
+		TotalKeysExamined   int64 `bson:"totalKeysExamined"`
+		TotalDocsExamined   int64 `bson:"totalDocsExamined"`
+	} `bson:"executionStats"`
+}
+
+type planStage struct {
+	Stage       string       `bson:"stage"`
+	IndexName   string       `bson:"indexName"`
+	InputStage  *planStage   `bson:"inputStage"`
+	InputStages []*planStage `bson:"inputStages"`
+	// QueryPlan wraps stages of slot based engine since 5.0
+	QueryPlan *planStage `bson:"queryPlan"`
+	// Shards are plans of shards under SHARD_MERGE stage
+	Shards []struct {
+		WinningPlan *planStage `bson:"winningPlan"`
+	} `bson:"shards"`
+}
+
+// ParsePlan parses server's explain output
+func ParsePlan(raw bson.Raw) (*Plan, error) {
+	var out explainOutput
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("parse explain: %w", err)
+	}
+
+	plan := &Plan{
+		KeysExamined:  out.ExecutionStats.TotalKeysExamined,
+		DocsExamined:  out.ExecutionStats.TotalDocsExamined,
+		Returned:      out.ExecutionStats.NReturned,
+		ExecutionTime: time.Duration(out.ExecutionStats.ExecutionTimeMillis) * time.Millisecond,
+		Raw:           raw,
+	}
+	plan.walk(&out.QueryPlanner.WinningPlan)
+	if len(plan.Stages) > 0 {
+		plan.Stage = plan.Stages[0]
+	}
+	return plan, nil
+}
+
+func (p *Plan) walk(s *planStage) {
+	if s == nil {
+		return
+	}
+	if s.QueryPlan != nil {
+		p.walk(s.QueryPlan)
+		return
+	}
+
+	if s.Stage != "" {
+		p.Stages = append(p.Stages, s.Stage)
+	}
+	if s.Stage == StageIxScan && s.IndexName != "" {
+		p.Indexes = append(p.Indexes, s.IndexName)
+	}
+	p.walk(s.InputStage)
+	for _, input := range s.InputStages {
+		p.walk(input)
+	}
+	for _, shard := range s.Shards {
+		p.walk(shard.WinningPlan)
+	}
+}
+
+// Explain returns plan of query that Find runs with the same options, rec is a model or pointer to slice of models
+func (w *dbWrapper) Explain(ctx context.Context, rec interface{}, opts []opt.FnOpt, verbosity ExplainVerbosity) (plan *Plan, err error) {
+	coll, err := w.getCollection(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.validate(rec, opts); err != nil {
+		return nil, err
+	}
+
+	o := opt.New(opts...)
+	ctx, end := w.startOperation(ctx, "Explain", coll, rec, o.GetFilter())
+	defer func() { err = end(err) }()
+
+	return explain(ctx, coll, o, verbosity)
+}
+
+func explain(ctx context.Context, coll *mongo.Collection, o *opt.Opt, verbosity ExplainVerbosity) (*Plan, error) {
+	if verbosity == "" {
+		verbosity = ExplainQueryPlanner
+	}
+
+	cmd := bson.D{
+		{Key: "explain", Value: findCommand(coll.Name(), o)},
+		{Key: "verbosity", Value: string(verbosity)},
+	}
+	raw, err := coll.Database().RunCommand(ctx, cmd).DecodeBytes()
+	if err != nil {
+		return nil, err
+	}
+	return ParsePlan(raw)
+}
+
+// findCommand builds find command like driver does for Find with options
+func findCommand(coll string, o *opt.Opt) bson.D {
+	filter := o.GetFilter()
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cmd := bson.D{{Key: "find", Value: coll}, {Key: "filter", Value: filter}}
+	find := o.GetOptions()
+	if find.Sort != nil {
+		cmd = append(cmd, bson.E{Key: "sort", Value: find.Sort})
+	}
+	if find.Projection != nil {
+		cmd = append(cmd, bson.E{Key: "projection", Value: find.Projection})
+	}
+	if find.Skip != nil {
+		cmd = append(cmd, bson.E{Key: "skip", Value: *find.Skip})
+	}
+	if find.Limit != nil {
+		cmd = append(cmd, bson.E{Key: "limit", Value: *find.Limit})
+	}
+	if find.Hint != nil {
+		cmd = append(cmd, bson.E{Key: "hint", Value: find.Hint})
+	}
+	if find.Collation != nil {
+		cmd = append(cmd, bson.E{Key: "collation", Value: find.Collation.ToDocument()})
+	}
+	if find.MaxTime != nil {
+		cmd = append(cmd, bson.E{Key: "maxTimeMS", Value: find.MaxTime.Milliseconds()})
+	}
+	if find.Comment != nil {
+		cmd = append(cmd, bson.E{Key: "comment", Value: *find.Comment})
+	}
+	if find.AllowDiskUse != nil {
+		cmd = append(cmd, bson.E{Key: "allowDiskUse", Value: *find.AllowDiskUse})
+	}
+	return cmd
+}
+
+// inSessionTransaction responds whether context has transaction of client or of driver's session
+func inSessionTransaction(ctx context.Context) bool {
+	if InTransaction(ctx) {
+		return true
+	}
+	if sess, ok := mongo.SessionFromContext(ctx).(mongo.XSession); ok {
+		return sess.ClientSession().TransactionRunning()
+	}
+	return false
+}
+
+// collScanGuard explains queries on collections and reports collection scans
+type collScanGuard struct {
+	fail        bool
+	collections map[string]bool
+}
+
+// checkCollScan explains query before it's sent, explain errors are logged and don't fail query,
+// queries in transaction aren't checked since server rejects explain there
+func (w *dbWrapper) checkCollScan(ctx context.Context, coll *mongo.Collection, rec interface{}, o *opt.Opt) error {
+	g := w.collScan
+	if g == nil || len(g.collections) > 0 && !g.collections[coll.Name()] || inSessionTransaction(ctx) {
+		return nil
+	}
+
+	plan, err := explain(ctx, coll, o, ExplainQueryPlanner)
+	if err != nil {
+		w.log(ctx).Error("explain query failed", err, "collection", coll.Name())
+		return nil
+	}
+	if !plan.IsCollScan() {
+		return nil
+	}
+
+	if g.fail {
+		return fmt.Errorf("%w of %s.%s by %s", ErrCollScan, coll.Database().Name(), coll.Name(), modelName(rec))
+	}
+
+	var filter bson.M
+	if raw, err := bson.Marshal(o.GetFilter()); err == nil {
+		filter = redactFilter(raw)
+	}
+	w.log(ctx).Warn("collection scan",
+		"database", coll.Database().Name(),
+		"collection", coll.Name(),
+		"model", modelName(rec),
+		"filter", filter,
+	)
+	return nil
+}
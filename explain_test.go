@@ -0,0 +1,150 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+	"time"
+)
+
+func TestParsePlan(t *testing.T) {
+	cases := []struct {
+		name     string
+		output   bson.M
+		stages   []string
+		indexes  []string
+		collScan bool
+	}{
+		{
+			name: "index scan",
+			output: bson.M{
+				"queryPlanner": bson.M{"winningPlan": bson.M{
+					"stage":      "FETCH",
+					"inputStage": bson.M{"stage": "IXSCAN", "indexName": "name_1"},
+				}},
+				"executionStats": bson.M{
+					"nReturned":           int32(2),
+					"executionTimeMillis": int32(5),
+					"totalKeysExamined":   int32(2),
+					"totalDocsExamined":   int32(2),
+				},
+			},
+			stages:  []string{"FETCH", "IXSCAN"},
+			indexes: []string{"name_1"},
+		},
+		{
+			name: "slot based engine",
+			output: bson.M{"queryPlanner": bson.M{"winningPlan": bson.M{
+				"queryPlan":     bson.M{"stage": "COLLSCAN"},
+				"slotBasedPlan": bson.M{"stages": "scan"},
+			}}},
+			stages:   []string{"COLLSCAN"},
+			collScan: true,
+		},
+		{
+			name: "or",
+			output: bson.M{"queryPlanner": bson.M{"winningPlan": bson.M{
+				"stage": "SUBPLAN",
+				"inputStage": bson.M{"stage": "OR", "inputStages": bson.A{
+					bson.M{"stage": "IXSCAN", "indexName": "name_1"},
+					bson.M{"stage": "IXSCAN", "indexName": "value_1"},
+				}},
+			}}},
+			stages:  []string{"SUBPLAN", "OR", "IXSCAN", "IXSCAN"},
+			indexes: []string{"name_1", "value_1"},
+		},
+		{
+			name: "sharded",
+			output: bson.M{"queryPlanner": bson.M{"winningPlan": bson.M{
+				"stage": "SHARD_MERGE",
+				"shards": bson.A{
+					bson.M{"shardName": "a", "winningPlan": bson.M{"stage": "COLLSCAN"}},
+				},
+			}}},
+			stages:   []string{"SHARD_MERGE", "COLLSCAN"},
+			collScan: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			raw, err := bson.Marshal(c.output)
+			require.NoError(t, err)
+
+			plan, err := ParsePlan(raw)
+			require.NoError(t, err)
+			require.Equal(t, c.stages[0], plan.Stage)
+			require.Equal(t, c.stages, plan.Stages)
+			require.Equal(t, c.indexes, plan.Indexes)
+			require.Equal(t, c.collScan, plan.IsCollScan())
+		})
+	}
+
+	raw, _ := bson.Marshal(cases[0].output)
+	plan, err := ParsePlan(raw)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), plan.Returned)
+	require.Equal(t, int64(2), plan.DocsExamined)
+	require.Equal(t, int64(2), plan.KeysExamined)
+	require.Equal(t, 5*time.Millisecond, plan.ExecutionTime)
+}
+
+func TestFindCommand(t *testing.T) {
+	cmd := findCommand("books", opt.New(
+		opt.Eq("name", "hello"),
+		opt.Desc("value"),
+		opt.Paging(2, 10),
+		opt.Hint("name_1"),
+		opt.MaxTime(time.Second),
+	))
+
+	require.Equal(t, bson.D{
+		{Key: "find", Value: "books"},
+		{Key: "filter", Value: bson.M{"name": bson.M{"$eq": "hello"}}},
+		{Key: "sort", Value: bson.D{{Key: "value", Value: -1}}},
+		{Key: "skip", Value: int64(10)},
+		{Key: "limit", Value: int64(10)},
+		{Key: "hint", Value: "name_1"},
+		{Key: "maxTimeMS", Value: int64(1000)},
+	}, cmd)
+
+	require.Equal(t, bson.D{{Key: "find", Value: "books"}, {Key: "filter", Value: bson.M{}}}, findCommand("books", opt.New()))
+}
+
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Debug(msg string, _ ...interface{}) { l.messages = append(l.messages, msg) }
+func (l *recordingLogger) Info(msg string, _ ...interface{})  { l.messages = append(l.messages, msg) }
+func (l *recordingLogger) Warn(msg string, _ ...interface{})  { l.messages = append(l.messages, msg) }
+func (l *recordingLogger) Error(msg string, _ error, _ ...interface{}) {
+	l.messages = append(l.messages, msg)
+}
+
+func TestCollScanGuard_Skipped(t *testing.T) {
+	mc, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err)
+	log := &recordingLogger{}
+	client := NewFromClient(mc, "test", WithLogger(log), WithTopology(TopologyStandalone), WithCollScanGuard(true))
+	w := client.(*dbWrapper)
+	coll := w.db.Collection("places")
+
+	// explain on disconnected client fails and is logged
+	require.NoError(t, w.checkCollScan(context.Background(), coll, &placeItem{}, opt.New()))
+	require.Equal(t, []string{"explain query failed"}, log.messages)
+
+	log.messages = nil
+	txCtx, end := NewTxContext(context.Background())
+	defer end(false)
+	require.NoError(t, w.checkCollScan(txCtx, coll, &placeItem{}, opt.New()))
+
+	_, err = client.Count(context.Background(), &placeItem{}, opt.List(opt.Near("location", model.NewPoint(2.35, 48.85), 1000, 0)))
+	require.Equal(t, errCountNear, err)
+	require.Empty(t, log.messages)
+}
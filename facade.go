@@ -8,8 +8,11 @@ import (
 
 type IClient interface {
 	DB() *mongo.Database
+	WithDatabase(name string) IClient
+	Topology() Topology
 	Ping(ctx context.Context) error
 	WithTX(ctx context.Context, fn func(context.Context) error) error
+	WithTXOptions(ctx context.Context, txOpts *TxOptions, fn func(context.Context) error) error
 	Close() error
 
 	Create(ctx context.Context, rec interface{}) error
@@ -20,4 +23,10 @@ type IClient interface {
 	DeleteWhere(ctx context.Context, rec interface{}, opts []opt.FnOpt) (int64, error)
 	FindByID(ctx context.Context, rec interface{}) error
 	Find(ctx context.Context, rec interface{}, opts []opt.FnOpt) error
+	FindOne(ctx context.Context, rec interface{}, opts []opt.FnOpt) error
+	Count(ctx context.Context, rec interface{}, opts []opt.FnOpt) (int64, error)
+	Aggregate(ctx context.Context, rec interface{}, pipeline interface{}, opts []opt.FnOpt) error
+	Explain(ctx context.Context, rec interface{}, opts []opt.FnOpt, verbosity ExplainVerbosity) (*Plan, error)
+
+	EnsureIndexes(ctx context.Context, models ...interface{}) error
 }
@@ -0,0 +1,161 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Loader loads fixture files keyed by collection name into database:
+//
+//	books:
+//	  - _id: '{{oid "war-and-peace"}}'
+//	    name: War and Peace
+//	    authorId: '{{oid "tolstoy"}}'
+//	    createdAt: '{{now}}'
+type Loader struct {
+	db     *mongo.Database
+	paths  []string
+	dirs   []string
+	models []interface{}
+
+	// collections maps collection name to database name
+	collections map[string]string
+	fixtures    map[string][]interface{}
+
+	mx  sync.Mutex
+	ids map[string]primitive.ObjectID
+}
+
+// New creates loader and parses fixture files
+func New(db *mongo.Database, optFn ...OptionFn) (*Loader, error) {
+	l := &Loader{
+		db:          db,
+		collections: make(map[string]string),
+		fixtures:    make(map[string][]interface{}),
+		ids:         make(map[string]primitive.ObjectID),
+	}
+	for _, opt := range optFn {
+		opt(l)
+	}
+
+	for _, m := range l.models {
+		coll, dbName, err := mongodb.CollectionName(m)
+		if err != nil {
+			return nil, fmt.Errorf("fixtures model %T: %w", m, err)
+		}
+		l.collections[coll] = dbName
+	}
+
+	for _, dir := range l.dirs {
+		for _, ext := range []string{"*.yml", "*.yaml", "*.json"} {
+			paths, err := filepath.Glob(filepath.Join(dir, ext))
+			if err != nil {
+				return nil, err
+			}
+			l.paths = append(l.paths, paths...)
+		}
+	}
+
+	for _, path := range l.paths {
+		if err := l.parseFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// ID returns ObjectID used for name in `oid` template function
+func (l *Loader) ID(name string) primitive.ObjectID {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	id, ok := l.ids[name]
+	if !ok {
+		id = primitive.NewObjectID()
+		l.ids[name] = id
+	}
+	return id
+}
+
+// Load truncates fixtures collections and inserts fixtures
+func (l *Loader) Load(ctx context.Context) error {
+	collections := make([]string, 0, len(l.fixtures))
+	for coll := range l.fixtures {
+		collections = append(collections, coll)
+	}
+	sort.Strings(collections)
+
+	for _, name := range collections {
+		docs := make([]interface{}, 0, len(l.fixtures[name]))
+		for _, doc := range l.fixtures[name] {
+			v, err := l.evaluate(doc)
+			if err != nil {
+				return fmt.Errorf("fixtures %s: %w", name, err)
+			}
+			docs = append(docs, v)
+		}
+
+		coll := l.collection(name)
+		if _, err := coll.DeleteMany(ctx, bson.M{}); err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			continue
+		}
+		if _, err := coll.InsertMany(ctx, docs); err != nil {
+			return fmt.Errorf("fixtures %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) collection(name string) *mongo.Collection {
+	if dbName := l.collections[name]; dbName != "" && dbName != l.db.Name() {
+		return l.db.Client().Database(dbName).Collection(name)
+	}
+	return l.db.Collection(name)
+}
+
+func (l *Loader) parseFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	fixtures := map[string][]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &fixtures)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&fixtures)
+	default:
+		err = fmt.Errorf("unknown format")
+	}
+	if err != nil {
+		return fmt.Errorf("fixtures file %s: %w", path, err)
+	}
+
+	for coll, docs := range fixtures {
+		if len(l.models) > 0 {
+			if _, ok := l.collections[coll]; !ok {
+				return fmt.Errorf("fixtures file %s: unknown collection %s", path, coll)
+			}
+		}
+		l.fixtures[coll] = append(l.fixtures[coll], docs...)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package fixtures
+
+import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/mongotest"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+type testBook struct {
+	collection         struct{} `bson:"books"`
+	model.DefaultModel `bson:",inline"`
+}
+
+func TestLoader_Parse(t *testing.T) {
+	l, err := New(nil, Directory("testdata"))
+	require.NoError(t, err)
+	require.Len(t, l.fixtures, 3)
+
+	book, err := l.evaluate(l.fixtures["books"][0])
+	require.NoError(t, err)
+	doc := book.(map[string]interface{})
+	require.Equal(t, l.ID("war-and-peace"), doc["_id"])
+	require.Equal(t, l.ID("tolstoy"), doc["authorId"])
+	require.Equal(t, "book-"+l.ID("war-and-peace").Hex(), doc["code"])
+	require.Equal(t, 1225, doc["value"])
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), doc["createdAt"].(time.Time), time.Minute)
+	require.Equal(t, time.Date(1869, 1, 1, 0, 0, 0, 0, time.UTC), doc["publishedAt"])
+
+	review, err := l.evaluate(l.fixtures["reviews"][0])
+	require.NoError(t, err)
+	doc = review.(map[string]interface{})
+	require.Equal(t, l.ID("war-and-peace"), doc["bookId"])
+	require.Equal(t, int64(5), doc["rating"])
+	require.Equal(t, 4.5, doc["score"])
+	require.NotEqual(t, primitive.NilObjectID, l.ID("other"))
+}
+
+func TestLoader_Models(t *testing.T) {
+	_, err := New(nil, Files("testdata/books.yml"), WithModels(&testBook{}))
+	require.EqualError(t, err, "fixtures file testdata/books.yml: unknown collection authors")
+}
+
+func TestLoader_Load(t *testing.T) {
+	ctx := context.Background()
+	db := mongotest.RunServer(t).Client(t).DB()
+
+	_, err := db.Collection("books").InsertOne(ctx, bson.M{"name": "stale"})
+	require.NoError(t, err)
+
+	l, err := New(db, Directory("testdata"))
+	require.NoError(t, err)
+	require.NoError(t, l.Load(ctx))
+	// second load truncates collections instead of failing on duplicate ids
+	require.NoError(t, l.Load(ctx))
+
+	for coll, count := range map[string]int64{"authors": 1, "books": 1, "reviews": 1} {
+		n, err := db.Collection(coll).CountDocuments(ctx, bson.M{})
+		require.NoError(t, err)
+		require.Equal(t, count, n, coll)
+	}
+
+	var book bson.M
+	require.NoError(t, db.Collection("books").FindOne(ctx, bson.M{"_id": l.ID("war-and-peace")}).Decode(&book))
+	require.Equal(t, "War and Peace", book["name"])
+	require.Equal(t, l.ID("tolstoy"), book["authorId"])
+}
@@ -0,0 +1,24 @@
+package fixtures
+
+type OptionFn func(l *Loader)
+
+// Files adds fixture files, format is detected by extension (.yml, .yaml or .json)
+func Files(paths ...string) OptionFn {
+	return func(l *Loader) {
+		l.paths = append(l.paths, paths...)
+	}
+}
+
+// Directory adds all fixture files from directory
+func Directory(dir string) OptionFn {
+	return func(l *Loader) {
+		l.dirs = append(l.dirs, dir)
+	}
+}
+
+// WithModels restricts fixtures to collections of models, model's database tag is used for its collection
+func WithModels(models ...interface{}) OptionFn {
+	return func(l *Loader) {
+		l.models = append(l.models, models...)
+	}
+}
@@ -0,0 +1,108 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// templateRe matches `{{fn "arg"}}` actions
+var templateRe = regexp.MustCompile(`{{\s*(\w+)((?:\s+"[^"]*")*)\s*}}`)
+
+var argRe = regexp.MustCompile(`"([^"]*)"`)
+
+// evaluate replaces template actions in fixture values, value that consists
+// of single action gets typed result, e.g. ObjectID or time
+func (l *Loader) evaluate(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			r, err := l.evaluate(item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			res[key] = r
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(val))
+		for _, item := range val {
+			r, err := l.evaluate(item)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, r)
+		}
+		return res, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		return val.Float64()
+	case string:
+		return l.evaluateString(val)
+	default:
+		return v, nil
+	}
+}
+
+func (l *Loader) evaluateString(s string) (interface{}, error) {
+	if m := templateRe.FindStringSubmatch(s); m != nil && m[0] == strings.TrimSpace(s) {
+		return l.call(m[1], m[2])
+	}
+
+	var callErr error
+	res := templateRe.ReplaceAllStringFunc(s, func(action string) string {
+		m := templateRe.FindStringSubmatch(action)
+		v, err := l.call(m[1], m[2])
+		if err != nil {
+			callErr = err
+			return action
+		}
+		switch val := v.(type) {
+		case primitive.ObjectID:
+			return val.Hex()
+		case time.Time:
+			return val.Format(time.RFC3339)
+		default:
+			return fmt.Sprint(v)
+		}
+	})
+	return res, callErr
+}
+
+func (l *Loader) call(fn, rawArgs string) (interface{}, error) {
+	var args []string
+	for _, m := range argRe.FindAllStringSubmatch(rawArgs, -1) {
+		args = append(args, m[1])
+	}
+
+	switch fn {
+	case "now":
+		now := time.Now().UTC()
+		if len(args) == 0 {
+			return now, nil
+		}
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return now.Add(d), nil
+	case "oid":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("oid needs name")
+		}
+		return l.ID(args[0]), nil
+	case "date":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("date needs RFC3339 value")
+		}
+		return time.Parse(time.RFC3339, args[0])
+	default:
+		return nil, fmt.Errorf("unknown template function %s", fn)
+	}
+}
@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operation is a client's method call
+type Operation struct {
+	// Name is a client's method name, e.g. Create, Find or WithTX
+	Name       string
+	Database   string
+	Collection string
+	// Model is a type of model passed to client's method
+	Model string
+	// Filter is a query filter with redacted values
+	Filter bson.M
+}
+
+// Hook is called around client's operations, e.g. for tracing or metrics
+type Hook interface {
+	// Before is called before operation, returned context is used for operation
+	Before(ctx context.Context, op *Operation) context.Context
+	// After is called with operation result
+	After(ctx context.Context, op *Operation, err error)
+}
+
+// startOperation calls hooks before operation and returns function to finish it,
+// the function classifies operation error and passes it to hooks
+func (w *dbWrapper) startOperation(ctx context.Context, name string, coll *mongo.Collection, rec interface{}, filter interface{}) (context.Context, func(error) error) {
+	ctx = withModel(ctx, rec)
+	if len(w.hooks) == 0 {
+		return ctx, ClassifyError
+	}
+
+	op := &Operation{Name: name, Database: w.db.Name(), Model: modelName(rec)}
+	if coll != nil {
+		op.Database = coll.Database().Name()
+		op.Collection = coll.Name()
+	}
+	if filter != nil {
+		if raw, err := bson.Marshal(filter); err == nil {
+			op.Filter = redactFilter(raw)
+		}
+	}
+
+	for _, h := range w.hooks {
+		ctx = h.Before(ctx, op)
+	}
+	return ctx, func(err error) error {
+		err = ClassifyError(err)
+		for i := len(w.hooks) - 1; i >= 0; i-- {
+			w.hooks[i].After(ctx, op, err)
+		}
+		return err
+	}
+}
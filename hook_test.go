@@ -0,0 +1,44 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type recordHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordHook) Before(ctx context.Context, op *Operation) context.Context {
+	*h.calls = append(*h.calls, h.name+" before "+op.Collection+"."+op.Name)
+	return ctx
+}
+
+func (h recordHook) After(_ context.Context, _ *Operation, err error) {
+	*h.calls = append(*h.calls, h.name+" after "+err.Error())
+}
+
+func TestStartOperation(t *testing.T) {
+	mc, err := mongo.NewClient()
+	require.NoError(t, err)
+
+	var calls []string
+	w := newWrapper(false, WithHook(recordHook{"a", &calls}), WithHook(recordHook{"b", &calls}))
+	w.db = mc.Database("test")
+
+	ctx, end := w.startOperation(context.Background(), "Find", w.db.Collection("books"), &[]*monitorItem{}, bson.M{"name": "secret"})
+	require.Equal(t, "mongodb.monitorItem", modelFromContext(ctx))
+	require.EqualError(t, end(errors.New("boom")), "boom")
+
+	require.Equal(t, []string{
+		"a before books.Find",
+		"b before books.Find",
+		"b after boom",
+		"a after boom",
+	}, calls)
+}
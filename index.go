@@ -0,0 +1,116 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const tagIndex = "index"
+
+// EnsureIndexes creates indexes declared by `index` tag of models' fields, existing indexes are kept
+func (w *dbWrapper) EnsureIndexes(ctx context.Context, models ...interface{}) error {
+	for _, rec := range models {
+		if err := w.ensureIndexes(ctx, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *dbWrapper) ensureIndexes(ctx context.Context, rec interface{}) (err error) {
+	coll, err := w.getCollection(rec)
+	if err != nil {
+		return err
+	}
+
+	indexes, err := ModelIndexes(rec)
+	if err != nil || len(indexes) == 0 {
+		return err
+	}
+
+	ctx, end := w.startOperation(ctx, "EnsureIndexes", coll, rec, nil)
+	defer func() { err = end(err) }()
+
+	_, err = coll.Indexes().CreateMany(ctx, indexes)
+	return err
+}
+
+// ModelIndexes returns indexes declared by `index` tag of model's fields, tag has index type
+// asc, desc, hashed or 2dsphere and optional unique and sparse flags, e.g. `index:"asc,unique"`,
+// fields with text type are joined into one text index and could have weight, e.g. `index:"text,weight=10"`
+func ModelIndexes(rec interface{}) ([]mongo.IndexModel, error) {
+	t := reflect.TypeOf(rec)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errIncorrectModelInterface
+	}
+
+	var indexes []mongo.IndexModel
+	var textKeys bson.D
+	textWeights := bson.M{}
+	for _, f := range model.Fields(rec) {
+		name, field := f.Name, f.StructField
+		tag, ok := field.Tag.Lookup(tagIndex)
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		var kind interface{}
+		switch parts[0] {
+		case "", "asc":
+			kind = 1
+		case "desc":
+			kind = -1
+		case "hashed", "2dsphere", "text":
+			kind = parts[0]
+		default:
+			return nil, fmt.Errorf("unknown index type %q of field %s", parts[0], field.Name)
+		}
+
+		opts := options.Index()
+		for _, flag := range parts[1:] {
+			switch {
+			case (flag == "unique" || flag == "sparse") && kind == "text":
+				return nil, fmt.Errorf("index flag %q is not supported by text index of field %s", flag, field.Name)
+			case flag == "unique":
+				opts.SetUnique(true)
+			case flag == "sparse":
+				opts.SetSparse(true)
+			case kind == "text" && strings.HasPrefix(flag, "weight="):
+				weight, err := strconv.Atoi(strings.TrimPrefix(flag, "weight="))
+				if err != nil || weight < 1 {
+					return nil, fmt.Errorf("incorrect text index weight %q of field %s", flag, field.Name)
+				}
+				textWeights[name] = weight
+			default:
+				return nil, fmt.Errorf("unknown index flag %q of field %s", flag, field.Name)
+			}
+		}
+
+		if kind == "text" {
+			// collection could have only one text index
+			textKeys = append(textKeys, bson.E{Key: name, Value: kind})
+			continue
+		}
+		indexes = append(indexes, mongo.IndexModel{Keys: bson.D{{Key: name, Value: kind}}, Options: opts})
+	}
+
+	if len(textKeys) > 0 {
+		opts := options.Index()
+		if len(textWeights) > 0 {
+			opts.SetWeights(textWeights)
+		}
+		indexes = append(indexes, mongo.IndexModel{Keys: textKeys, Options: opts})
+	}
+	return indexes, nil
+}
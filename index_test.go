@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type placeItem struct {
+	collection         struct{} `bson:"places"`
+	model.DefaultModel `bson:",inline"`
+	Name               string      `bson:"name" index:"asc,unique"`
+	Location           model.Point `bson:"location" index:"2dsphere"`
+	Rating             int         `index:"desc,sparse"`
+	Owner              string      `bson:"owner" index:"hashed"`
+	Comment            string      `bson:"comment"`
+	Title              string      `bson:"title" index:"text,weight=10"`
+	Description        string      `bson:"description" index:"text"`
+}
+
+type categoryItem struct {
+	collection struct{}      `bson:"categories"`
+	Name       string        `bson:"name" index:"asc"`
+	Parent     *categoryItem `bson:"parent"`
+}
+
+func TestModelIndexes(t *testing.T) {
+	indexes, err := ModelIndexes(&[]*placeItem{})
+	require.NoError(t, err)
+	require.Len(t, indexes, 5)
+
+	require.Equal(t, bson.D{{Key: "name", Value: 1}}, indexes[0].Keys)
+	require.True(t, *indexes[0].Options.Unique)
+	require.Equal(t, bson.D{{Key: "location", Value: "2dsphere"}}, indexes[1].Keys)
+	require.Equal(t, bson.D{{Key: "rating", Value: -1}}, indexes[2].Keys)
+	require.True(t, *indexes[2].Options.Sparse)
+	require.Nil(t, indexes[2].Options.Unique)
+	require.Equal(t, bson.D{{Key: "owner", Value: "hashed"}}, indexes[3].Keys)
+	require.Equal(t, bson.D{{Key: "title", Value: "text"}, {Key: "description", Value: "text"}}, indexes[4].Keys)
+	require.Equal(t, bson.M{"title": 10}, indexes[4].Options.Weights)
+
+	_, err = ModelIndexes(&struct {
+		Name string `bson:"name" index:"geo"`
+	}{})
+	require.EqualError(t, err, `unknown index type "geo" of field Name`)
+
+	indexes, err = ModelIndexes(&categoryItem{})
+	require.NoError(t, err)
+	require.Len(t, indexes, 1)
+	require.NoError(t, ValidateOptions(&categoryItem{}, opt.Eq("parent.parent.name", "books")))
+
+	_, err = ModelIndexes(&struct {
+		Title string `bson:"title" index:"text,unique"`
+	}{})
+	require.EqualError(t, err, `index flag "unique" is not supported by text index of field Title`)
+}
@@ -0,0 +1,38 @@
+package logger
+
+import "context"
+
+// Logger is a logger facade, keyvals are pairs of field name and value
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, err error, keyvals ...interface{})
+}
+
+type ctxKey struct{}
+
+// ToContext returns context with logger
+func ToContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns logger from context or fallback one
+func FromContext(ctx context.Context, fallback Logger) Logger {
+	if l, ok := ctx.Value(ctxKey{}).(Logger); ok && l != nil {
+		return l
+	}
+	return fallback
+}
+
+// Nop returns logger that drops everything
+func Nop() Logger {
+	return nop{}
+}
+
+type nop struct{}
+
+func (nop) Debug(string, ...interface{})        {}
+func (nop) Info(string, ...interface{})         {}
+func (nop) Warn(string, ...interface{})         {}
+func (nop) Error(string, error, ...interface{}) {}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestZerolog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewZerolog(zerolog.New(buf))
+
+	l.Error("failed", errors.New("boom"), "collection", "books", "odd")
+	require.JSONEq(t, `{"level":"error","error":"boom","collection":"books","odd":null,"message":"failed"}`, buf.String())
+}
+
+func TestGlobalZerolog(t *testing.T) {
+	l := NewGlobalZerolog()
+
+	prev := log.Logger
+	defer func() { log.Logger = prev }()
+	buf := &bytes.Buffer{}
+	log.Logger = zerolog.New(buf)
+
+	l.Warn("slow query", "collection", "books")
+	require.JSONEq(t, `{"level":"warn","collection":"books","message":"slow query"}`, buf.String())
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := Nop()
+	require.Equal(t, fallback, FromContext(context.Background(), fallback))
+
+	l := NewZerolog(zerolog.Nop())
+	require.Equal(t, l, FromContext(ToContext(context.Background(), l), fallback))
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogAdapter struct {
+	logger *slog.Logger
+}
+
+// NewSlog returns log/slog adapter
+func NewSlog(logger *slog.Logger) Logger {
+	return &slogAdapter{logger: logger}
+}
+
+func (a *slogAdapter) Debug(msg string, keyvals ...interface{}) {
+	a.logger.Log(context.Background(), slog.LevelDebug, msg, keyvals...)
+}
+
+func (a *slogAdapter) Info(msg string, keyvals ...interface{}) {
+	a.logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+func (a *slogAdapter) Warn(msg string, keyvals ...interface{}) {
+	a.logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+func (a *slogAdapter) Error(msg string, err error, keyvals ...interface{}) {
+	a.logger.Log(context.Background(), slog.LevelError, msg, append([]interface{}{"error", err}, keyvals...)...)
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+func TestSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	l := NewSlog(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	l.Debug("dropped")
+	l.Error("failed", errors.New("boom"), "collection", "books")
+	require.JSONEq(t, `{"level":"ERROR","msg":"failed","error":"boom","collection":"books"}`, buf.String())
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type zerologAdapter struct {
+	logger zerolog.Logger
+}
+
+// NewZerolog returns zerolog adapter
+func NewZerolog(logger zerolog.Logger) Logger {
+	return &zerologAdapter{logger: logger}
+}
+
+// NewGlobalZerolog returns adapter of global zerolog logger, it's resolved on every call,
+// so logger set to log.Logger after adapter is created is used
+func NewGlobalZerolog() Logger {
+	return globalZerolog{}
+}
+
+type globalZerolog struct{}
+
+func (globalZerolog) Debug(msg string, keyvals ...interface{}) {
+	NewZerolog(log.Logger).Debug(msg, keyvals...)
+}
+
+func (globalZerolog) Info(msg string, keyvals ...interface{}) {
+	NewZerolog(log.Logger).Info(msg, keyvals...)
+}
+
+func (globalZerolog) Warn(msg string, keyvals ...interface{}) {
+	NewZerolog(log.Logger).Warn(msg, keyvals...)
+}
+
+func (globalZerolog) Error(msg string, err error, keyvals ...interface{}) {
+	NewZerolog(log.Logger).Error(msg, err, keyvals...)
+}
+
+func (a *zerologAdapter) Debug(msg string, keyvals ...interface{}) {
+	withFields(a.logger.Debug(), keyvals).Msg(msg)
+}
+
+func (a *zerologAdapter) Info(msg string, keyvals ...interface{}) {
+	withFields(a.logger.Info(), keyvals).Msg(msg)
+}
+
+func (a *zerologAdapter) Warn(msg string, keyvals ...interface{}) {
+	withFields(a.logger.Warn(), keyvals).Msg(msg)
+}
+
+func (a *zerologAdapter) Error(msg string, err error, keyvals ...interface{}) {
+	withFields(a.logger.Error().Err(err), keyvals).Msg(msg)
+}
+
+func withFields(e *zerolog.Event, keyvals []interface{}) *zerolog.Event {
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		if i+1 == len(keyvals) {
+			e = e.Interface(key, nil)
+			break
+		}
+		e = e.Interface(key, keyvals[i+1])
+	}
+	return e
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"go.mongodb.org/mongo-driver/event"
+	"time"
+)
+
+// Metrics collects operations latency, errors and connection pool state,
+// it should be registered in prometheus registry by caller
+type Metrics struct {
+	namespace string
+	buckets   []float64
+
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+	open     *prometheus.GaugeVec
+	inUse    *prometheus.GaugeVec
+	waiting  *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+var _ mongodb.Hook = (*Metrics)(nil)
+
+// New creates metrics
+func New(options ...OptionFn) *Metrics {
+	m := &Metrics{
+		namespace: "mongodb",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range options {
+		opt(m)
+	}
+
+	operationLabels := []string{"database", "collection", "operation"}
+	m.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of client operations.",
+		Buckets:   m.buckets,
+	}, operationLabels)
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "operation_errors_total",
+		Help:      "Number of failed client operations.",
+	}, operationLabels)
+
+	poolLabels := []string{"address"}
+	m.open = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.namespace,
+		Name:      "pool_connections_open",
+		Help:      "Number of open connections in pool.",
+	}, poolLabels)
+	m.inUse = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.namespace,
+		Name:      "pool_connections_in_use",
+		Help:      "Number of connections checked out from pool.",
+	}, poolLabels)
+	m.waiting = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.namespace,
+		Name:      "pool_wait_queue",
+		Help:      "Number of operations waiting for connection.",
+	}, poolLabels)
+
+	return m
+}
+
+// Describe implements prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.duration.Describe(ch)
+	m.errors.Describe(ch)
+	m.open.Describe(ch)
+	m.inUse.Describe(ch)
+	m.waiting.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.duration.Collect(ch)
+	m.errors.Collect(ch)
+	m.open.Collect(ch)
+	m.inUse.Collect(ch)
+	m.waiting.Collect(ch)
+}
+
+type startKey struct{}
+
+// Before implements mongodb.Hook
+func (m *Metrics) Before(ctx context.Context, _ *mongodb.Operation) context.Context {
+	return context.WithValue(ctx, startKey{}, time.Now())
+}
+
+// After implements mongodb.Hook
+func (m *Metrics) After(ctx context.Context, op *mongodb.Operation, err error) {
+	labels := prometheus.Labels{"database": op.Database, "collection": op.Collection, "operation": op.Name}
+	if start, ok := ctx.Value(startKey{}).(time.Time); ok {
+		m.duration.With(labels).Observe(time.Since(start).Seconds())
+	}
+	if err != nil {
+		m.errors.With(labels).Inc()
+	}
+}
+
+// PoolMonitor returns monitor that should be set to client options
+func (m *Metrics) PoolMonitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: m.onPoolEvent}
+}
+
+func (m *Metrics) onPoolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		m.open.WithLabelValues(e.Address).Inc()
+	case event.ConnectionClosed:
+		m.open.WithLabelValues(e.Address).Dec()
+	case event.GetStarted:
+		m.waiting.WithLabelValues(e.Address).Inc()
+	case event.GetFailed:
+		m.waiting.WithLabelValues(e.Address).Dec()
+	case event.GetSucceeded:
+		m.waiting.WithLabelValues(e.Address).Dec()
+		m.inUse.WithLabelValues(e.Address).Inc()
+	case event.ConnectionReturned:
+		m.inUse.WithLabelValues(e.Address).Dec()
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/event"
+	"testing"
+)
+
+func TestMetrics_Hook(t *testing.T) {
+	m := New(WithNamespace("test"))
+	require.NoError(t, prometheus.NewRegistry().Register(m))
+
+	op := &mongodb.Operation{Name: "Find", Database: "test", Collection: "books"}
+	m.After(m.Before(context.Background(), op), op, nil)
+	m.After(m.Before(context.Background(), op), op, errors.New("boom"))
+
+	require.Equal(t, 1, testutil.CollectAndCount(m.duration))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.errors.WithLabelValues("test", "books", "Find")))
+}
+
+func TestMetrics_Pool(t *testing.T) {
+	m := New()
+	pm := m.PoolMonitor()
+	for _, typ := range []string{
+		event.ConnectionCreated,
+		event.ConnectionCreated,
+		event.GetStarted,
+		event.GetStarted,
+		event.GetSucceeded,
+		event.ConnectionClosed,
+	} {
+		pm.Event(&event.PoolEvent{Type: typ, Address: "localhost:27017"})
+	}
+
+	require.Equal(t, float64(1), testutil.ToFloat64(m.open.WithLabelValues("localhost:27017")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.inUse.WithLabelValues("localhost:27017")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m.waiting.WithLabelValues("localhost:27017")))
+}
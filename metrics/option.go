@@ -0,0 +1,17 @@
+package metrics
+
+type OptionFn func(m *Metrics)
+
+// WithNamespace sets metrics namespace, default is `mongodb`
+func WithNamespace(namespace string) OptionFn {
+	return func(m *Metrics) {
+		m.namespace = namespace
+	}
+}
+
+// WithBuckets sets operation duration histogram buckets
+func WithBuckets(buckets []float64) OptionFn {
+	return func(m *Metrics) {
+		m.buckets = buckets
+	}
+}
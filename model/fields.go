@@ -0,0 +1,98 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const fieldCollection = "collection"
+
+// Field is a document field of model
+type Field struct {
+	// Name is a bson name, names of nested document fields are joined with dot, e.g. address.city
+	Name string
+	// Type is a field type without pointer
+	Type reflect.Type
+	// StructField is a field of model's struct
+	StructField reflect.StructField
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	geometryType = reflect.TypeOf((*Geometry)(nil)).Elem()
+)
+
+// Fields returns document fields of model, pointer to model or pointer to slice of models,
+// inline structs are flattened and fields of nested documents follow document field itself,
+// recursive types are not expanded again
+func Fields(rec interface{}) []Field {
+	t := reflect.TypeOf(rec)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return appendFields(nil, t, "", map[reflect.Type]bool{})
+}
+
+// appendFields adds fields of struct, visiting has types of enclosing documents to stop on cycles
+func appendFields(fields []Field, t reflect.Type, prefix string, visiting map[reflect.Type]bool) []Field {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Name == fieldCollection && prefix == "" {
+			continue
+		}
+
+		tag := sf.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+		name, flags := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, flags = tag[:i], tag[i+1:]
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// like driver, embedded structs without inline flag are nested documents
+		if ft.Kind() == reflect.Struct && strings.Contains(","+flags+",", ",inline,") {
+			if !visiting[ft] {
+				fields = appendFields(fields, ft, prefix, visiting)
+			}
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		fields = append(fields, Field{Name: prefix + name, Type: ft, StructField: sf})
+		if isDocument(ft) && !visiting[ft] {
+			fields = appendFields(fields, ft, prefix+name+".", visiting)
+		}
+	}
+	return fields
+}
+
+// isDocument responds whether struct is stored as nested document with queryable fields
+func isDocument(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType || t.Implements(geometryType) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
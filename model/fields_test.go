@@ -0,0 +1,94 @@
+package model
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fieldsAddress struct {
+	City   string `bson:"city"`
+	Street string
+}
+
+type fieldsInline struct {
+	collection   struct{} `bson:"books"`
+	DefaultModel `bson:",inline"`
+	Name         string        `bson:"name"`
+	Address      fieldsAddress `bson:"address"`
+	Location     Point         `bson:"location"`
+	Skipped      string        `bson:"-"`
+}
+
+type fieldsEmbedded struct {
+	collection struct{} `bson:"books"`
+	DefaultModel
+	Name string `bson:"name"`
+}
+
+func TestFields_MatchMarshal(t *testing.T) {
+	models := []interface{}{
+		&fieldsInline{
+			DefaultModel: DefaultModel{IDField: IDField{ID: primitive.NewObjectID()}, DateFields: DateFields{CreatedAt: time.Now(), UpdatedAt: time.Now()}},
+			Name:         "hello",
+			Address:      fieldsAddress{City: "Paris", Street: "Rivoli"},
+			Location:     NewPoint(2.35, 48.85),
+		},
+		&fieldsEmbedded{
+			DefaultModel: DefaultModel{IDField: IDField{ID: primitive.NewObjectID()}, DateFields: DateFields{CreatedAt: time.Now(), UpdatedAt: time.Now()}},
+			Name:         "hello",
+		},
+	}
+
+	for _, rec := range models {
+		data, err := bson.Marshal(rec)
+		require.NoError(t, err)
+		var doc bson.D
+		require.NoError(t, bson.Unmarshal(data, &doc))
+
+		var names []string
+		for _, f := range Fields(rec) {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		require.Equal(t, documentKeys(doc, ""), names)
+	}
+}
+
+// documentKeys returns paths of document fields, paths of nested documents are included
+// except geometries which are queried as a whole
+func documentKeys(doc bson.D, prefix string) []string {
+	var keys []string
+	for _, e := range doc {
+		keys = append(keys, prefix+e.Key)
+		if sub, ok := e.Value.(bson.D); ok && e.Key != "location" {
+			keys = append(keys, documentKeys(sub, prefix+e.Key+".")...)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type fieldsCategory struct {
+	collection struct{}        `bson:"categories"`
+	Name       string          `bson:"name"`
+	Parent     *fieldsCategory `bson:"parent"`
+	Children   []fieldsCategory
+	Owner      fieldsOwner `bson:"owner"`
+}
+
+type fieldsOwner struct {
+	Name     string          `bson:"name"`
+	Category *fieldsCategory `bson:"category"`
+}
+
+func TestFields_Recursive(t *testing.T) {
+	var names []string
+	for _, f := range Fields(&fieldsCategory{}) {
+		names = append(names, f.Name)
+	}
+	require.Equal(t, []string{"name", "parent", "children", "owner", "owner.name", "owner.category"}, names)
+}
@@ -0,0 +1,68 @@
+package model
+
+// Geometry is a GeoJSON object, it's stored in field with `index:"2dsphere"` tag
+type Geometry interface {
+	GeoJSONType() string
+}
+
+// Point is a GeoJSON point, coordinates are longitude and latitude
+type Point struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// LineString is a GeoJSON line of two or more points
+type LineString struct {
+	Type        string      `json:"type" bson:"type"`
+	Coordinates [][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// Polygon is a GeoJSON polygon, first ring is exterior and others are holes,
+// each ring must be closed, i.e. first and last points are equal
+type Polygon struct {
+	Type        string        `json:"type" bson:"type"`
+	Coordinates [][][]float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewPoint creates point by longitude and latitude
+func NewPoint(lng, lat float64) Point {
+	return Point{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+// NewLineString creates line by [lng, lat] points
+func NewLineString(points ...[]float64) LineString {
+	return LineString{Type: "LineString", Coordinates: points}
+}
+
+// NewPolygon creates polygon by rings of [lng, lat] points
+func NewPolygon(rings ...[][]float64) Polygon {
+	return Polygon{Type: "Polygon", Coordinates: rings}
+}
+
+// Lng returns longitude of point
+func (p Point) Lng() float64 {
+	if len(p.Coordinates) < 2 {
+		return 0
+	}
+	return p.Coordinates[0]
+}
+
+// Lat returns latitude of point
+func (p Point) Lat() float64 {
+	if len(p.Coordinates) < 2 {
+		return 0
+	}
+	return p.Coordinates[1]
+}
+
+func (p Point) GeoJSONType() string {
+	return "Point"
+}
+
+func (l LineString) GeoJSONType() string {
+	return "LineString"
+}
+
+func (p Polygon) GeoJSONType() string {
+	return "Polygon"
+}
@@ -0,0 +1,426 @@
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"sort"
+)
+
+const codeDuplicateKey = 11000
+
+var (
+	errNestedTransaction = errors.New("transaction already started")
+	errAggregate         = errors.New("aggregation is not supported by in-memory client")
+	errExplain           = errors.New("explain is not supported by in-memory client")
+)
+
+// Client is an in-memory IClient implementation for unit tests,
+// documents are stored as BSON and filters are evaluated in memory
+type Client struct {
+	store  *store
+	dbName string
+}
+
+var _ mongodb.IClient = (*Client)(nil)
+
+// New creates empty in-memory client with default database
+func New(dbName string) *Client {
+	return &Client{store: newStore(), dbName: dbName}
+}
+
+// DB returns nil, there is no real database behind client
+func (c *Client) DB() *mongo.Database {
+	return nil
+}
+
+func (c *Client) WithDatabase(name string) mongodb.IClient {
+	return &Client{store: c.store, dbName: name}
+}
+
+func (c *Client) Topology() mongodb.Topology {
+	return mongodb.TopologyReplicaSet
+}
+
+func (c *Client) Ping(context.Context) error {
+	return nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) WithTX(ctx context.Context, fn func(context.Context) error) error {
+	return c.WithTXOptions(ctx, nil, fn)
+}
+
+// WithTXOptions runs fn on snapshot of all databases and restores it on error,
+// writes made outside of transaction meanwhile are lost on rollback
+func (c *Client) WithTXOptions(ctx context.Context, txOpts *mongodb.TxOptions, fn func(context.Context) error) error {
+	if mongodb.InTransaction(ctx) {
+		if txOpts != nil && txOpts.Nested == mongodb.NestedFail {
+			return errNestedTransaction
+		}
+		return fn(ctx)
+	}
+
+	snapshot := c.store.snapshot()
+	txCtx, end := mongodb.NewTxContext(ctx)
+	if err := fn(txCtx); err != nil {
+		c.store.restore(snapshot)
+		end(false)
+		return err
+	}
+
+	end(true)
+	return nil
+}
+
+func (c *Client) Create(_ context.Context, rec interface{}) error {
+	ns, elem, err := c.namespaceAndModel(rec)
+	if err != nil {
+		return err
+	}
+
+	elem.Creating()
+	doc, err := toDocument(rec)
+	if err != nil {
+		return err
+	}
+
+	id, ok := lookupKey(doc, "_id")
+	if !ok {
+		id = primitive.NewObjectID()
+		doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	if _, found := c.store.findByID(ns, id); found {
+		return mongodb.ClassifyError(duplicateKeyError(ns, id))
+	}
+	if err := c.store.insert(ns, doc); err != nil {
+		return err
+	}
+
+	elem.SetID(id)
+	return nil
+}
+
+func (c *Client) Update(_ context.Context, rec interface{}) error {
+	ns, elem, err := c.namespaceAndModel(rec)
+	if err != nil {
+		return err
+	}
+
+	elem.Updating()
+	set, err := toDocument(rec)
+	if err != nil {
+		return err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	i, found := c.store.findByID(ns, elem.GetID())
+	if !found {
+		return nil
+	}
+	return c.store.set(ns, i, set)
+}
+
+func (c *Client) Upsert(_ context.Context, rec interface{}) error {
+	ns, elem, err := c.namespaceAndModel(rec)
+	if err != nil {
+		return err
+	}
+
+	if elem.IsNew() {
+		elem.Creating()
+	} else {
+		elem.Updating()
+	}
+
+	set, err := toDocument(rec)
+	if err != nil {
+		return err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	id := elem.GetID()
+	if i, found := c.store.findByID(ns, id); found {
+		return c.store.set(ns, i, set)
+	}
+
+	// upserted document gets _id from filter like $set upsert does
+	if _, ok := lookupKey(set, "_id"); !ok {
+		set = append(bson.D{{Key: "_id", Value: id}}, set...)
+	}
+	if err := c.store.insert(ns, set); err != nil {
+		return err
+	}
+
+	elem.SetID(id)
+	return nil
+}
+
+// UpdateWhere behaves like dbWrapper that sends empty update document
+func (c *Client) UpdateWhere(_ context.Context, rec interface{}, _ []opt.FnOpt) (int64, error) {
+	if _, _, err := c.namespaceAndModel(rec); err != nil {
+		return 0, err
+	}
+	return 0, mongo.ErrNilDocument
+}
+
+func (c *Client) Delete(_ context.Context, rec interface{}) error {
+	ns, elem, err := c.namespaceAndModel(rec)
+	if err != nil {
+		return err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	if i, found := c.store.findByID(ns, elem.GetID()); found {
+		c.store.remove(ns, i)
+	}
+	return nil
+}
+
+func (c *Client) DeleteWhere(_ context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
+	ns, err := c.namespace(rec)
+	if err != nil {
+		return 0, err
+	}
+
+	filter, err := normalize(opt.GetFilter(opts...))
+	if err != nil {
+		return 0, err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	var deleted int64
+	docs := c.store.documents(ns)
+	for i := len(docs) - 1; i >= 0; i-- {
+		ok, err := matchDocument(docs[i], filter)
+		if err != nil {
+			return deleted, err
+		}
+		if ok {
+			c.store.remove(ns, i)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (c *Client) FindByID(_ context.Context, rec interface{}) error {
+	ns, elem, err := c.namespaceAndModel(rec)
+	if err != nil {
+		return err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+	i, found := c.store.findByID(ns, elem.GetID())
+	if !found {
+		return mongodb.ClassifyError(mongo.ErrNoDocuments)
+	}
+	return bson.Unmarshal(c.store.raw(ns, i), rec)
+}
+
+func (c *Client) Find(_ context.Context, rec interface{}, opts []opt.FnOpt) error {
+	ns, err := c.namespace(rec)
+	if err != nil {
+		return err
+	}
+
+	raws, err := c.find(ns, opt.New(opts...))
+	if err != nil {
+		return err
+	}
+	return decodeAll(raws, rec)
+}
+
+func (c *Client) FindOne(_ context.Context, rec interface{}, opts []opt.FnOpt) error {
+	ns, err := c.namespace(rec)
+	if err != nil {
+		return err
+	}
+
+	o := opt.New(opts...)
+	o.Limit = 1
+	raws, err := c.find(ns, o)
+	if err != nil {
+		return err
+	}
+	if len(raws) == 0 {
+		return mongodb.ClassifyError(mongo.ErrNoDocuments)
+	}
+	return bson.Unmarshal(raws[0], rec)
+}
+
+// Count ignores paging like the driver's CountDocuments
+func (c *Client) Count(_ context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
+	ns, err := c.namespace(rec)
+	if err != nil {
+		return 0, err
+	}
+
+	o := opt.New(opts...)
+	o.Skip, o.Limit = 0, 0
+	raws, err := c.find(ns, o)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(raws)), nil
+}
+
+// find returns sorted page of documents matching options, query options like hints are ignored
+func (c *Client) find(ns namespace, o *opt.Opt) ([]bson.Raw, error) {
+	filter, err := normalize(o.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	c.store.Lock()
+	defer c.store.Unlock()
+
+	var found []int
+	docs := c.store.documents(ns)
+	for i, doc := range docs {
+		ok, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			found = append(found, i)
+		}
+	}
+
+	if o.IsSorting() {
+		sort.SliceStable(found, func(i, j int) bool {
+			a, _ := lookup(docs[found[i]], o.SortBy)
+			b, _ := lookup(docs[found[j]], o.SortBy)
+			return compareOrder(a, b)*o.SortOrder < 0
+		})
+	}
+
+	raws := make([]bson.Raw, 0, len(found))
+	for _, i := range paginate(found, o.Skip, o.Limit) {
+		raws = append(raws, c.store.raw(ns, i))
+	}
+	return raws, nil
+}
+
+// Aggregate is not supported, use Recorder to stub aggregation results
+func (c *Client) Aggregate(_ context.Context, rec interface{}, _ interface{}, _ []opt.FnOpt) error {
+	if _, err := c.namespace(rec); err != nil {
+		return err
+	}
+	return errAggregate
+}
+
+// Explain is not supported, use Recorder to stub query plans
+func (c *Client) Explain(_ context.Context, rec interface{}, _ []opt.FnOpt, _ mongodb.ExplainVerbosity) (*mongodb.Plan, error) {
+	if _, err := c.namespace(rec); err != nil {
+		return nil, err
+	}
+	return nil, errExplain
+}
+
+// EnsureIndexes only validates index declarations, unique indexes except _id are not enforced
+func (c *Client) EnsureIndexes(_ context.Context, models ...interface{}) error {
+	for _, rec := range models {
+		if _, _, err := c.namespaceAndModel(rec); err != nil {
+			return err
+		}
+		if _, err := mongodb.ModelIndexes(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) namespace(rec interface{}) (namespace, error) {
+	coll, db, err := mongodb.CollectionName(rec)
+	if err != nil {
+		return namespace{}, err
+	}
+	if db == "" {
+		db = c.dbName
+	}
+	return namespace{database: db, collection: coll}, nil
+}
+
+func (c *Client) namespaceAndModel(rec interface{}) (namespace, model.Model, error) {
+	ns, err := c.namespace(rec)
+	if err != nil {
+		return ns, nil, err
+	}
+
+	elem, ok := rec.(model.Model)
+	if !ok {
+		return ns, nil, fmt.Errorf("%T is not a model", rec)
+	}
+	return ns, elem, nil
+}
+
+func paginate(items []int, skip, limit int64) []int {
+	if skip >= int64(len(items)) {
+		return nil
+	}
+	items = items[skip:]
+	if limit > 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
+
+func decodeAll(raws []bson.Raw, rec interface{}) error {
+	v := reflect.ValueOf(rec).Elem()
+	res := reflect.MakeSlice(v.Type(), 0, len(raws))
+	for _, raw := range raws {
+		item := reflect.New(v.Type().Elem())
+		if err := bson.Unmarshal(raw, item.Interface()); err != nil {
+			return err
+		}
+		res = reflect.Append(res, item.Elem())
+	}
+	v.Set(res)
+	return nil
+}
+
+func toDocument(rec interface{}) (bson.D, error) {
+	raw, err := bson.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	err = bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func lookupKey(doc bson.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func duplicateKeyError(ns namespace, id interface{}) error {
+	return mongo.WriteException{WriteErrors: mongo.WriteErrors{{
+		Code:    codeDuplicateKey,
+		Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: _id_ dup key: { _id: %v }", ns, id),
+	}}}
+}
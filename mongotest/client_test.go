@@ -0,0 +1,217 @@
+package mongotest
+
+import (
+	"context"
+	"errors"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+type testItem struct {
+	collection         struct{} `bson:"books"`
+	model.DefaultModel `bson:",inline"`
+	Name               string   `bson:"name"`
+	Value              int      `bson:"value"`
+	Tags               []string `bson:"tags"`
+}
+
+type archiveItem struct {
+	collection         struct{} `bson:"books" database:"archive"`
+	model.DefaultModel `bson:",inline"`
+	Name               string `bson:"name"`
+}
+
+func TestClient_CRUD(t *testing.T) {
+	ctx := context.Background()
+	client := New("test")
+
+	item := &testItem{Name: "hello", Value: 123}
+	require.NoError(t, client.Create(ctx, item))
+	require.NotEmpty(t, item.ID)
+	require.NotEmpty(t, item.CreatedAt)
+
+	item.Value = 456
+	require.NoError(t, client.Update(ctx, item))
+
+	v := &testItem{}
+	v.SetID(item.GetID())
+	require.NoError(t, client.FindByID(ctx, v))
+	require.Equal(t, item.Name, v.Name)
+	require.Equal(t, 456, v.Value)
+
+	err := client.Create(ctx, item)
+	require.True(t, mongo.IsDuplicateKeyError(err))
+	var dupErr *mongodb.DuplicateKeyError
+	require.ErrorAs(t, err, &dupErr)
+	require.Equal(t, "_id_", dupErr.Index)
+
+	require.NoError(t, client.Delete(ctx, item))
+	require.ErrorIs(t, client.FindByID(ctx, v), mongodb.ErrNotFound)
+	require.ErrorIs(t, client.FindByID(ctx, v), mongo.ErrNoDocuments)
+}
+
+func TestClient_Upsert(t *testing.T) {
+	ctx := context.Background()
+	client := New("test")
+
+	item := &testItem{Name: "hello"}
+	require.NoError(t, client.Upsert(ctx, item))
+
+	item.Name = "bye"
+	require.NoError(t, client.Upsert(ctx, item))
+
+	arr := []*testItem{}
+	require.NoError(t, client.Find(ctx, &arr, nil))
+	require.Len(t, arr, 1)
+	require.Equal(t, "bye", arr[0].Name)
+}
+
+func TestClient_Find(t *testing.T) {
+	ctx := context.Background()
+	client := New("test")
+	for i, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		require.NoError(t, client.Create(ctx, &testItem{Name: name, Value: i, Tags: []string{name[:1], "all"}}))
+	}
+
+	cases := []struct {
+		name  string
+		opts  []opt.FnOpt
+		names []string
+	}{
+		{
+			name:  "eq",
+			opts:  opt.List(opt.Eq("name", "beta")),
+			names: []string{"beta"},
+		},
+		{
+			name:  "range sorted desc",
+			opts:  opt.List(opt.Ge("value", 1), opt.Lt("value", 3), opt.Desc("value")),
+			names: []string{"gamma", "beta"},
+		},
+		{
+			name:  "in",
+			opts:  opt.List(opt.In("name", []string{"alpha", "delta", "omega"}), opt.Asc("name")),
+			names: []string{"alpha", "delta"},
+		},
+		{
+			name:  "or contains",
+			opts:  opt.List(opt.Or(opt.Contains("name", "MM"), opt.Eq("value", 0)), opt.Asc("createdAt")),
+			names: []string{"alpha", "gamma"},
+		},
+		{
+			name:  "array element",
+			opts:  opt.List(opt.Eq("tags", "d")),
+			names: []string{"delta"},
+		},
+		{
+			name:  "paging",
+			opts:  opt.List(opt.Neq("name", "alpha"), opt.Asc("name"), opt.Paging(2, 1)),
+			names: []string{"delta"},
+		},
+		{
+			name:  "exists size all",
+			opts:  opt.List(opt.Exists("tags", true), opt.Size("tags", 2), opt.All("tags", []string{"all", "g"})),
+			names: []string{"gamma"},
+		},
+		{
+			name:  "elem match",
+			opts:  opt.List(opt.ElemMatch("tags", opt.Regex("", "^[ab]$", ""))),
+			names: []string{"alpha", "beta"},
+		},
+		{
+			name:  "mod type not in",
+			opts:  opt.List(opt.Mod("value", 2, 1), opt.Type("value", "int"), opt.NotIn("name", "delta")),
+			names: []string{"beta"},
+		},
+		{
+			name:  "regex flags",
+			opts:  opt.List(opt.Regex("name", "^GA", "i")),
+			names: []string{"gamma"},
+		},
+		{
+			name:  "prefix literal",
+			opts:  opt.List(opt.Prefix("name", "de"), opt.ContainsLiteral("name", "LT")),
+			names: []string{"delta"},
+		},
+		{
+			name:  "not",
+			opts:  opt.List(opt.Not(opt.Gt("value", 0), opt.Lt("value", 3)), opt.Not(opt.Eq("name", "delta"), opt.Eq("value", 3)), opt.Asc("value")),
+			names: []string{"alpha"},
+		},
+		{
+			name:  "not null",
+			opts:  opt.List(opt.NotNull("name"), opt.IsNull("missing"), opt.Asc("value")),
+			names: []string{"alpha", "beta", "gamma", "delta"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			arr := []*testItem{}
+			require.NoError(t, client.Find(ctx, &arr, c.opts))
+
+			names := []string{}
+			for _, item := range arr {
+				names = append(names, item.Name)
+			}
+			require.Equal(t, c.names, names)
+		})
+	}
+
+	item := &testItem{}
+	require.NoError(t, client.FindOne(ctx, item, opt.List(opt.Gt("value", 0), opt.Desc("value"), opt.Hint("value_1"))))
+	require.Equal(t, "delta", item.Name)
+	require.ErrorIs(t, client.FindOne(ctx, item, opt.List(opt.Eq("name", "omega"))), mongodb.ErrNotFound)
+
+	count, err := client.Count(ctx, &testItem{}, opt.List(opt.Eq("tags", "all"), opt.Paging(1, 1)))
+	require.NoError(t, err)
+	require.Equal(t, int64(4), count)
+
+	n, err := client.DeleteWhere(ctx, &testItem{}, opt.List(opt.Gt("value", 1)))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+}
+
+func TestClient_Databases(t *testing.T) {
+	ctx := context.Background()
+	client := New("test")
+
+	require.NoError(t, client.Create(ctx, &archiveItem{Name: "old"}))
+	require.NoError(t, client.Create(ctx, &testItem{Name: "new"}))
+
+	arr := []*testItem{}
+	require.NoError(t, client.WithDatabase("archive").Find(ctx, &arr, nil))
+	require.Len(t, arr, 1)
+	require.Equal(t, "old", arr[0].Name)
+}
+
+func TestClient_WithTX(t *testing.T) {
+	ctx := context.Background()
+	client := New("test")
+
+	committed := false
+	err := client.WithTX(ctx, func(ctx context.Context) error {
+		mongodb.AfterCommit(ctx, func(context.Context) { committed = true })
+		return client.Create(ctx, &testItem{Name: "good"})
+	})
+	require.NoError(t, err)
+	require.True(t, committed)
+
+	rolledBack := false
+	err = client.WithTX(ctx, func(ctx context.Context) error {
+		mongodb.AfterRollback(ctx, func(context.Context) { rolledBack = true })
+		require.NoError(t, client.Create(ctx, &testItem{Name: "bad"}))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	require.True(t, rolledBack)
+
+	arr := []*testItem{}
+	require.NoError(t, client.Find(ctx, &arr, nil))
+	require.Len(t, arr, 1)
+	require.Equal(t, "good", arr[0].Name)
+}
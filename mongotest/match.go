@@ -0,0 +1,560 @@
+package mongotest
+
+import (
+	"bytes"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// normalize converts filter values to types they have after decoding from BSON
+func normalize(filter bson.M) (bson.M, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var res bson.M
+	err = bson.Unmarshal(raw, &res)
+	return res, err
+}
+
+func matchDocument(doc bson.M, filter bson.M) (bool, error) {
+	for key, cond := range filter {
+		ok, err := matchKey(doc, key, cond)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchKey(doc bson.M, key string, cond interface{}) (bool, error) {
+	switch key {
+	case "$and", "$or", "$nor":
+		conds, ok := cond.(primitive.A)
+		if !ok {
+			return false, fmt.Errorf("%s argument must be an array", key)
+		}
+
+		matched := 0
+		for _, c := range conds {
+			f, ok := c.(bson.M)
+			if !ok {
+				return false, fmt.Errorf("%s argument's entries must be objects", key)
+			}
+			ok, err := matchDocument(doc, f)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				matched++
+			}
+		}
+
+		switch key {
+		case "$and":
+			return matched == len(conds), nil
+		case "$or":
+			return matched > 0, nil
+		default:
+			return matched == 0, nil
+		}
+	}
+
+	if strings.HasPrefix(key, "$") {
+		return false, fmt.Errorf("unknown top level operator: %s", key)
+	}
+
+	val, found := lookup(doc, key)
+	return matchValue(val, found, cond)
+}
+
+func isOperatorDoc(cond interface{}) (bson.M, bool) {
+	doc, ok := cond.(bson.M)
+	if !ok || len(doc) == 0 {
+		return nil, false
+	}
+	for key := range doc {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func matchValue(val interface{}, found bool, cond interface{}) (bool, error) {
+	ops, ok := isOperatorDoc(cond)
+	if !ok {
+		return matchEq(val, found, cond), nil
+	}
+
+	for op, arg := range ops {
+		ok, err := matchOperator(val, found, op, arg, ops)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchOperator(val interface{}, found bool, op string, arg interface{}, ops bson.M) (bool, error) {
+	switch op {
+	case "$eq":
+		return matchEq(val, found, arg), nil
+	case "$ne":
+		return !matchEq(val, found, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		return matchAny(val, func(v interface{}) bool {
+			cmp, ok := compare(v, arg)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "$gt":
+				return cmp > 0
+			case "$gte":
+				return cmp >= 0
+			case "$lt":
+				return cmp < 0
+			default:
+				return cmp <= 0
+			}
+		}), nil
+	case "$in", "$nin":
+		args, ok := arg.(primitive.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", op)
+		}
+
+		in := false
+		for _, a := range args {
+			if matchEq(val, found, a) {
+				in = true
+				break
+			}
+		}
+		return in == (op == "$in"), nil
+	case "$exists":
+		return found == truthy(arg), nil
+	case "$regex":
+		options, _ := ops["$options"].(string)
+		re, err := compileRegex(arg, options)
+		if err != nil {
+			return false, err
+		}
+		return matchAny(val, func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		}), nil
+	case "$options":
+		if _, ok := ops["$regex"]; !ok {
+			return false, fmt.Errorf("$options needs a $regex")
+		}
+		return true, nil
+	case "$all":
+		args, ok := arg.(primitive.A)
+		if !ok {
+			return false, fmt.Errorf("$all needs an array")
+		}
+		for _, a := range args {
+			if !matchEq(val, found, a) {
+				return false, nil
+			}
+		}
+		return len(args) > 0, nil
+	case "$size":
+		size, ok := toFloat(arg)
+		if !ok {
+			return false, fmt.Errorf("$size needs a number")
+		}
+		arr, ok := val.(primitive.A)
+		return ok && float64(len(arr)) == size, nil
+	case "$elemMatch":
+		query, ok := arg.(bson.M)
+		if !ok {
+			return false, fmt.Errorf("$elemMatch needs an object")
+		}
+		arr, ok := val.(primitive.A)
+		if !ok {
+			return false, nil
+		}
+		for _, item := range arr {
+			ok, err := matchElement(item, query)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case "$mod":
+		args, ok := arg.(primitive.A)
+		if !ok || len(args) != 2 {
+			return false, fmt.Errorf("malformed mod, needs to be an array of 2 elements")
+		}
+		divisor, _ := toFloat(args[0])
+		remainder, _ := toFloat(args[1])
+		if divisor == 0 {
+			return false, fmt.Errorf("divisor cannot be 0")
+		}
+		return matchAny(val, func(v interface{}) bool {
+			f, ok := toFloat(v)
+			return ok && int64(f)%int64(divisor) == int64(remainder)
+		}), nil
+	case "$type":
+		types, ok := arg.(primitive.A)
+		if !ok {
+			types = primitive.A{arg}
+		}
+		anyType := func(v interface{}) bool {
+			for _, t := range types {
+				if hasType(v, t) {
+					return true
+				}
+			}
+			return false
+		}
+		return found && (anyType(val) || matchAny(val, anyType)), nil
+	case "$not":
+		if _, ok := isOperatorDoc(arg); !ok {
+			if _, ok := arg.(primitive.Regex); !ok {
+				return false, fmt.Errorf("$not needs a regex or a document")
+			}
+		}
+		ok, err := matchValue(val, found, arg)
+		return !ok, err
+	default:
+		return false, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// matchElement matches array element with $elemMatch query, query of operators applies to element itself
+func matchElement(item interface{}, query bson.M) (bool, error) {
+	if ops, ok := isOperatorDoc(query); ok {
+		if _, logical := ops["$and"]; !logical {
+			if _, logical = ops["$or"]; !logical {
+				return matchValue(item, true, ops)
+			}
+		}
+	}
+
+	doc, ok := item.(bson.M)
+	if !ok {
+		return false, nil
+	}
+	return matchDocument(doc, query)
+}
+
+// typeAliases maps $type aliases to BSON type numbers
+var typeAliases = map[string]int{
+	"double":    1,
+	"string":    2,
+	"object":    3,
+	"array":     4,
+	"binData":   5,
+	"objectId":  7,
+	"bool":      8,
+	"date":      9,
+	"null":      10,
+	"regex":     11,
+	"int":       16,
+	"timestamp": 17,
+	"long":      18,
+	"decimal":   19,
+}
+
+func hasType(v interface{}, t interface{}) bool {
+	if alias, ok := t.(string); ok {
+		if alias == "number" {
+			_, ok := toFloat(v)
+			return ok
+		}
+		num, ok := typeAliases[alias]
+		if !ok {
+			return false
+		}
+		t = num
+	}
+
+	num, ok := toFloat(t)
+	if !ok {
+		return false
+	}
+
+	var actual int
+	switch v.(type) {
+	case float64:
+		actual = 1
+	case string:
+		actual = 2
+	case bson.M:
+		actual = 3
+	case primitive.A:
+		actual = 4
+	case primitive.Binary:
+		actual = 5
+	case primitive.ObjectID:
+		actual = 7
+	case bool:
+		actual = 8
+	case primitive.DateTime:
+		actual = 9
+	case nil:
+		actual = 10
+	case primitive.Regex:
+		actual = 11
+	case int32:
+		actual = 16
+	case primitive.Timestamp:
+		actual = 17
+	case int64:
+		actual = 18
+	case primitive.Decimal128:
+		actual = 19
+	}
+	return actual == int(num)
+}
+
+// matchEq compares value with target, array value matches when any element equals
+func matchEq(val interface{}, found bool, target interface{}) bool {
+	if target == nil {
+		return !found || val == nil
+	}
+	if re, ok := target.(primitive.Regex); ok {
+		compiled, err := compileRegex(re.Pattern, re.Options)
+		return err == nil && matchAny(val, func(v interface{}) bool {
+			s, ok := v.(string)
+			return ok && compiled.MatchString(s)
+		})
+	}
+	if !found {
+		return false
+	}
+	if equal(val, target) {
+		return true
+	}
+	if arr, ok := val.(primitive.A); ok {
+		for _, v := range arr {
+			if equal(v, target) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchAny checks value or any element of array value
+func matchAny(val interface{}, fn func(interface{}) bool) bool {
+	if arr, ok := val.(primitive.A); ok {
+		for _, v := range arr {
+			if fn(v) {
+				return true
+			}
+		}
+		return false
+	}
+	return fn(val)
+}
+
+func compileRegex(pattern interface{}, options string) (*regexp.Regexp, error) {
+	var expr string
+	switch p := pattern.(type) {
+	case string:
+		expr = p
+	case primitive.Regex:
+		expr = p.Pattern
+		options += p.Options
+	default:
+		return nil, fmt.Errorf("$regex has to be a string")
+	}
+
+	flags := ""
+	for _, o := range options {
+		if o == 'i' || o == 'm' || o == 's' {
+			flags += string(o)
+		}
+	}
+	if flags != "" {
+		expr = "(?" + flags + ")" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case nil:
+		return false
+	default:
+		if f, ok := toFloat(v); ok {
+			return f != 0
+		}
+		return true
+	}
+}
+
+// lookup returns value by dotted path, arrays in path are expanded
+func lookup(doc bson.M, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case bson.M:
+			val, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			cur = val
+		case primitive.A:
+			if idx, err := strconv.Atoi(part); err == nil {
+				if idx < 0 || idx >= len(v) {
+					return nil, false
+				}
+				cur = v[idx]
+				continue
+			}
+
+			var res primitive.A
+			for _, item := range v {
+				if m, ok := item.(bson.M); ok {
+					if val, ok := m[part]; ok {
+						res = append(res, val)
+					}
+				}
+			}
+			if len(res) == 0 {
+				return nil, false
+			}
+			cur = res
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func equal(a, b interface{}) bool {
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare compares values of the same BSON type, numbers of any type are comparable
+func compare(a, b interface{}) (int, bool) {
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return compareFloat(fa, fb), true
+		}
+		return 0, false
+	}
+
+	switch va := a.(type) {
+	case nil:
+		return 0, b == nil
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb), true
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			switch {
+			case va == vb:
+				return 0, true
+			case vb:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case primitive.DateTime:
+		if vb, ok := b.(primitive.DateTime); ok {
+			return compareFloat(float64(va), float64(vb)), true
+		}
+	case primitive.Timestamp:
+		if vb, ok := b.(primitive.Timestamp); ok {
+			return primitive.CompareTimestamp(va, vb), true
+		}
+	case primitive.ObjectID:
+		if vb, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(va[:], vb[:]), true
+		}
+	}
+	return 0, false
+}
+
+// compareOrder compares values of any types by BSON sort order
+func compareOrder(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+	if cmp, ok := compare(a, b); ok {
+		return cmp
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func typeOrder(v interface{}) int {
+	if _, ok := toFloat(v); ok {
+		return 2
+	}
+
+	switch v.(type) {
+	case nil:
+		return 1
+	case string, primitive.Symbol:
+		return 3
+	case bson.M, bson.D:
+		return 4
+	case primitive.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	default:
+		return 12
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,336 @@
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"sync"
+)
+
+// Call is a recorded client's method call
+type Call struct {
+	// Method is a client's method name, e.g. Find
+	Method string
+	// Database is a name passed to WithDatabase or model's database tag, empty for default database
+	Database   string
+	Collection string
+	// Model is a type of model passed to method
+	Model string
+	// Filter is a query filter for methods with options or filter by _id
+	Filter bson.M
+	// Options are options passed to method
+	Options *opt.Opt
+	// Pipeline is an aggregation pipeline passed to Aggregate
+	Pipeline interface{}
+}
+
+// Stub sets result of matching calls
+type Stub struct {
+	method     string
+	collection string
+	err        error
+	count      int64
+	result     interface{}
+	fn         func(call Call, rec interface{}) error
+}
+
+// Return sets error returned by call
+func (s *Stub) Return(err error) *Stub {
+	s.err = err
+	return s
+}
+
+// ReturnCount sets number of documents returned by UpdateWhere, DeleteWhere and Count
+func (s *Stub) ReturnCount(n int64) *Stub {
+	s.count = n
+	return s
+}
+
+// Result sets model for FindByID and FindOne, slice of models for Find or plan for Explain that is copied to call's argument
+func (s *Stub) Result(result interface{}) *Stub {
+	s.result = result
+	return s
+}
+
+// Do sets function that handles call instead of stub's values
+func (s *Stub) Do(fn func(call Call, rec interface{}) error) *Stub {
+	s.fn = fn
+	return s
+}
+
+// Recorder is an IClient that records calls and returns stubbed results,
+// methods without stub succeed with empty result
+type Recorder struct {
+	log      *callLog
+	database string
+}
+
+// callLog is shared by recorders of different databases
+type callLog struct {
+	mx    sync.Mutex
+	calls []Call
+	stubs []*Stub
+}
+
+var _ mongodb.IClient = (*Recorder)(nil)
+
+// NewRecorder creates recorder
+func NewRecorder() *Recorder {
+	return &Recorder{log: &callLog{}}
+}
+
+// On adds stub for method calls on collection, empty collection matches any,
+// first added matching stub is used
+func (r *Recorder) On(method, collection string) *Stub {
+	r.log.mx.Lock()
+	defer r.log.mx.Unlock()
+
+	s := &Stub{method: method, collection: collection}
+	r.log.stubs = append(r.log.stubs, s)
+	return s
+}
+
+// Calls returns recorded calls
+func (r *Recorder) Calls() []Call {
+	r.log.mx.Lock()
+	defer r.log.mx.Unlock()
+	return append([]Call(nil), r.log.calls...)
+}
+
+// CallsOf returns recorded calls of method
+func (r *Recorder) CallsOf(method string) []Call {
+	var res []Call
+	for _, c := range r.Calls() {
+		if c.Method == method {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Reset removes recorded calls and stubs
+func (r *Recorder) Reset() {
+	r.log.mx.Lock()
+	defer r.log.mx.Unlock()
+	r.log.calls = nil
+	r.log.stubs = nil
+}
+
+// DB returns nil, there is no real database behind recorder
+func (r *Recorder) DB() *mongo.Database {
+	return nil
+}
+
+// WithDatabase returns recorder sharing calls and stubs, its calls have database name
+// unless model has database tag
+func (r *Recorder) WithDatabase(name string) mongodb.IClient {
+	return &Recorder{log: r.log, database: name}
+}
+
+func (r *Recorder) Topology() mongodb.Topology {
+	return mongodb.TopologyReplicaSet
+}
+
+func (r *Recorder) Ping(context.Context) error {
+	_, err := r.record(Call{Method: "Ping"}, nil)
+	return err
+}
+
+func (r *Recorder) Close() error {
+	_, err := r.record(Call{Method: "Close"}, nil)
+	return err
+}
+
+func (r *Recorder) WithTX(ctx context.Context, fn func(context.Context) error) error {
+	return r.WithTXOptions(ctx, nil, fn)
+}
+
+// WithTXOptions records call and runs fn in transaction context, stubbed error is returned after fn succeeds
+func (r *Recorder) WithTXOptions(ctx context.Context, txOpts *mongodb.TxOptions, fn func(context.Context) error) error {
+	if mongodb.InTransaction(ctx) {
+		if txOpts != nil && txOpts.Nested == mongodb.NestedFail {
+			return errNestedTransaction
+		}
+		return fn(ctx)
+	}
+
+	_, stubErr := r.record(Call{Method: "WithTX"}, nil)
+	txCtx, end := mongodb.NewTxContext(ctx)
+	err := fn(txCtx)
+	if err == nil {
+		err = stubErr
+	}
+	end(err == nil)
+	return err
+}
+
+func (r *Recorder) Create(_ context.Context, rec interface{}) error {
+	_, err := r.recordModel("Create", rec, nil, nil)
+	return err
+}
+
+func (r *Recorder) Update(_ context.Context, rec interface{}) error {
+	_, err := r.recordModel("Update", rec, idFilter(rec), nil)
+	return err
+}
+
+func (r *Recorder) UpdateWhere(_ context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
+	o := opt.New(opts...)
+	return r.recordModel("UpdateWhere", rec, o.GetFilter(), o)
+}
+
+func (r *Recorder) Upsert(_ context.Context, rec interface{}) error {
+	_, err := r.recordModel("Upsert", rec, idFilter(rec), nil)
+	return err
+}
+
+func (r *Recorder) Delete(_ context.Context, rec interface{}) error {
+	_, err := r.recordModel("Delete", rec, idFilter(rec), nil)
+	return err
+}
+
+func (r *Recorder) DeleteWhere(_ context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
+	o := opt.New(opts...)
+	return r.recordModel("DeleteWhere", rec, o.GetFilter(), o)
+}
+
+func (r *Recorder) FindByID(_ context.Context, rec interface{}) error {
+	_, err := r.recordModel("FindByID", rec, idFilter(rec), nil)
+	return err
+}
+
+func (r *Recorder) Find(_ context.Context, rec interface{}, opts []opt.FnOpt) error {
+	o := opt.New(opts...)
+	_, err := r.recordModel("Find", rec, o.GetFilter(), o)
+	return err
+}
+
+func (r *Recorder) FindOne(_ context.Context, rec interface{}, opts []opt.FnOpt) error {
+	o := opt.New(opts...)
+	_, err := r.recordModel("FindOne", rec, o.GetFilter(), o)
+	return err
+}
+
+func (r *Recorder) Count(_ context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
+	o := opt.New(opts...)
+	return r.recordModel("Count", rec, o.GetFilter(), o)
+}
+
+func (r *Recorder) Aggregate(_ context.Context, rec interface{}, pipeline interface{}, opts []opt.FnOpt) error {
+	call, err := newCall("Aggregate", rec, nil, opt.New(opts...))
+	if err != nil {
+		return err
+	}
+	call.Pipeline = pipeline
+	_, err = r.record(call, rec)
+	return err
+}
+
+// Explain records call, stub's result should be a *mongodb.Plan, empty plan is returned without stub
+func (r *Recorder) Explain(_ context.Context, rec interface{}, opts []opt.FnOpt, _ mongodb.ExplainVerbosity) (*mongodb.Plan, error) {
+	o := opt.New(opts...)
+	call, err := newCall("Explain", rec, o.GetFilter(), o)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &mongodb.Plan{}
+	if _, err := r.record(call, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// EnsureIndexes records call for each model
+func (r *Recorder) EnsureIndexes(_ context.Context, models ...interface{}) error {
+	for _, rec := range models {
+		if _, err := r.recordModel("EnsureIndexes", rec, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) recordModel(method string, rec interface{}, filter bson.M, o *opt.Opt) (int64, error) {
+	call, err := newCall(method, rec, filter, o)
+	if err != nil {
+		return 0, err
+	}
+	return r.record(call, rec)
+}
+
+func (r *Recorder) record(call Call, rec interface{}) (int64, error) {
+	if call.Database == "" {
+		call.Database = r.database
+	}
+	r.log.mx.Lock()
+	r.log.calls = append(r.log.calls, call)
+	var stub *Stub
+	for _, s := range r.log.stubs {
+		if s.method == call.Method && (s.collection == "" || s.collection == call.Collection) {
+			stub = s
+			break
+		}
+	}
+	r.log.mx.Unlock()
+
+	if stub == nil {
+		return 0, nil
+	}
+	if stub.fn != nil {
+		return stub.count, mongodb.ClassifyError(stub.fn(call, rec))
+	}
+	if stub.result != nil {
+		if err := setResult(rec, stub.result); err != nil {
+			return 0, err
+		}
+	}
+	return stub.count, mongodb.ClassifyError(stub.err)
+}
+
+func newCall(method string, rec interface{}, filter bson.M, o *opt.Opt) (Call, error) {
+	coll, db, err := mongodb.CollectionName(rec)
+	if err != nil {
+		return Call{}, err
+	}
+
+	t := reflect.TypeOf(rec)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	return Call{
+		Method:     method,
+		Database:   db,
+		Collection: coll,
+		Model:      t.String(),
+		Filter:     filter,
+		Options:    o,
+	}, nil
+}
+
+func idFilter(rec interface{}) bson.M {
+	if m, ok := rec.(interface{ GetID() interface{} }); ok {
+		return bson.M{"_id": m.GetID()}
+	}
+	return nil
+}
+
+// setResult copies result to pointer passed to client's method
+func setResult(rec, result interface{}) error {
+	dst := reflect.ValueOf(rec)
+	src := reflect.ValueOf(result)
+	if src.Kind() == reflect.Ptr && src.Type() == dst.Type() {
+		src = src.Elem()
+	}
+
+	if dst.Kind() != reflect.Ptr || !src.Type().AssignableTo(dst.Elem().Type()) {
+		return fmt.Errorf("can't set stub result %T to %T", result, rec)
+	}
+	dst.Elem().Set(src)
+	return nil
+}
@@ -0,0 +1,93 @@
+package mongotest
+
+import (
+	"context"
+	"errors"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	rec := NewRecorder()
+	rec.On("Find", "books").Result([]*testItem{{Name: "hello"}})
+	rec.On("Create", "").Return(errors.New("boom"))
+	rec.On("DeleteWhere", "books").ReturnCount(3)
+
+	arr := []*testItem{}
+	err := rec.Find(ctx, &arr, opt.List(
+		opt.Eq("name", "hello"),
+		opt.Desc("createdAt"),
+	))
+	require.NoError(t, err)
+	require.Len(t, arr, 1)
+	require.Equal(t, "hello", arr[0].Name)
+
+	require.EqualError(t, rec.Create(ctx, &testItem{}), "boom")
+
+	n, err := rec.DeleteWhere(ctx, &testItem{}, opt.List(opt.Lt("value", 10)))
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	calls := rec.CallsOf("Find")
+	require.Len(t, calls, 1)
+	require.Equal(t, "books", calls[0].Collection)
+	require.Equal(t, "mongotest.testItem", calls[0].Model)
+	require.Equal(t, bson.M{"name": bson.M{"$eq": "hello"}}, calls[0].Filter)
+	require.Equal(t, "createdAt", calls[0].Options.SortBy)
+	require.Equal(t, -1, calls[0].Options.SortOrder)
+	require.Len(t, rec.Calls(), 3)
+
+	rec.On("Explain", "books").Result(&mongodb.Plan{Stage: mongodb.StageCollScan, Stages: []string{mongodb.StageCollScan}})
+	plan, err := rec.Explain(ctx, &arr, opt.List(opt.Eq("name", "hello")), mongodb.ExplainQueryPlanner)
+	require.NoError(t, err)
+	require.True(t, plan.IsCollScan())
+	require.Len(t, rec.CallsOf("Explain"), 1)
+
+	rec.Reset()
+	require.Empty(t, rec.Calls())
+}
+
+func TestRecorder_Databases(t *testing.T) {
+	ctx := context.Background()
+	rec := NewRecorder()
+	reports := rec.WithDatabase("reports")
+
+	require.NoError(t, rec.Create(ctx, &testItem{}))
+	require.NoError(t, reports.Create(ctx, &testItem{}))
+	require.NoError(t, reports.Create(ctx, &archiveItem{}))
+
+	calls := rec.CallsOf("Create")
+	require.Len(t, calls, 3)
+	require.Equal(t, "", calls[0].Database)
+	require.Equal(t, "reports", calls[1].Database)
+	require.Equal(t, "archive", calls[2].Database)
+}
+
+func TestRecorder_WithTX(t *testing.T) {
+	ctx := context.Background()
+	rec := NewRecorder()
+
+	err := rec.WithTX(ctx, func(ctx context.Context) error {
+		return rec.Create(ctx, &testItem{})
+	})
+	require.NoError(t, err)
+
+	err = rec.WithTX(ctx, func(context.Context) error {
+		return errors.New("rollback")
+	})
+	require.EqualError(t, err, "rollback")
+
+	rec.On("WithTX", "").Return(errors.New("commit failed"))
+	require.EqualError(t, rec.WithTX(ctx, func(context.Context) error { return nil }), "commit failed")
+
+	calls := rec.Calls()
+	require.Len(t, calls, 4)
+	require.Equal(t, "WithTX", calls[0].Method)
+	require.Equal(t, "Create", calls[1].Method)
+	require.Equal(t, "WithTX", calls[2].Method)
+	require.Equal(t, "WithTX", calls[3].Method)
+}
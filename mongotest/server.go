@@ -0,0 +1,261 @@
+package mongotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	defaultBinary       = "mongod"
+	defaultReplicaSet   = "rs0"
+	defaultStartTimeout = 30 * time.Second
+	// maxOutput is how many last bytes of mongod output are kept for startup error
+	maxOutput = 4096
+)
+
+var errServerNotFound = errors.New("mongod binary not found")
+
+type ServerOptionFn func(s *Server)
+
+// WithBinary sets path to mongod binary, default is mongod found on PATH
+func WithBinary(path string) ServerOptionFn {
+	return func(s *Server) {
+		s.binary = path
+	}
+}
+
+// WithReplicaSet starts server as single-node replica set, so transactions work
+func WithReplicaSet() ServerOptionFn {
+	return func(s *Server) {
+		s.replicaSet = defaultReplicaSet
+	}
+}
+
+// WithStartTimeout sets how long to wait for server start
+func WithStartTimeout(timeout time.Duration) ServerOptionFn {
+	return func(s *Server) {
+		s.startTimeout = timeout
+	}
+}
+
+// Server is a mongod process started on random port with temporary data directory
+type Server struct {
+	binary       string
+	replicaSet   string
+	startTimeout time.Duration
+
+	port   int
+	dbPath string
+	cmd    *exec.Cmd
+	output *tailBuffer
+}
+
+// RunServer starts server for test and stops it on cleanup, test is skipped when mongod is not found
+func RunServer(t testing.TB, optFn ...ServerOptionFn) *Server {
+	t.Helper()
+
+	s, err := StartServer(optFn...)
+	if errors.Is(err, errServerNotFound) {
+		t.Skip(err.Error())
+	}
+	if err != nil {
+		t.Fatalf("can't start mongod: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Errorf("can't stop mongod: %v", err)
+		}
+	})
+	return s
+}
+
+// StartServer starts mongod and waits until it is ready
+func StartServer(optFn ...ServerOptionFn) (*Server, error) {
+	s := &Server{binary: defaultBinary, startTimeout: defaultStartTimeout}
+	for _, opt := range optFn {
+		opt(s)
+	}
+
+	binary, err := exec.LookPath(s.binary)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errServerNotFound, err)
+	}
+
+	if s.port, err = freePort(); err != nil {
+		return nil, err
+	}
+	if s.dbPath, err = ioutil.TempDir("", "mongotest"); err != nil {
+		return nil, err
+	}
+
+	args := []string{"--port", strconv.Itoa(s.port), "--dbpath", s.dbPath, "--bind_ip", "127.0.0.1"}
+	if s.replicaSet != "" {
+		args = append(args, "--replSet", s.replicaSet)
+	}
+
+	s.output = &tailBuffer{size: maxOutput}
+	s.cmd = exec.Command(binary, args...)
+	s.cmd.Stdout = s.output
+	s.cmd.Stderr = s.output
+	if err := s.cmd.Start(); err != nil {
+		_ = os.RemoveAll(s.dbPath)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.startTimeout)
+	defer cancel()
+	if err := s.waitReady(ctx); err != nil {
+		_ = s.Stop()
+		return nil, fmt.Errorf("%w, mongod output:\n%s", err, s.output.String())
+	}
+	return s, nil
+}
+
+// URI returns connection string of server
+func (s *Server) URI() string {
+	if s.replicaSet != "" {
+		return fmt.Sprintf("mongodb://127.0.0.1:%d/?replicaSet=%s", s.port, s.replicaSet)
+	}
+	return fmt.Sprintf("mongodb://127.0.0.1:%d", s.port)
+}
+
+// Client connects to database unique for test that is dropped on cleanup
+func (s *Server) Client(t testing.TB, optFn ...mongodb.OptionFn) mongodb.IClient {
+	t.Helper()
+
+	cfg, err := mongodb.ParseURL(s.URI())
+	if err != nil {
+		t.Fatalf("can't parse uri: %v", err)
+	}
+	cfg.Database = databaseName(t.Name())
+
+	client, err := mongodb.Connect("mongotest", cfg, optFn...)
+	if err != nil {
+		t.Fatalf("can't connect to mongod: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.DB().Drop(context.Background())
+		_ = client.Close()
+	})
+	return client
+}
+
+// Stop kills server and removes its data directory
+func (s *Server) Stop() error {
+	if s.cmd != nil && s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+		_ = s.cmd.Wait()
+	}
+	return os.RemoveAll(s.dbPath)
+}
+
+func (s *Server) waitReady(ctx context.Context) error {
+	uri := fmt.Sprintf("mongodb://127.0.0.1:%d/?directConnection=true", s.port)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	for {
+		if err = client.Ping(ctx, nil); err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("mongod is not ready: %w", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if s.replicaSet == "" {
+		return nil
+	}
+	return s.initiateReplicaSet(ctx, client)
+}
+
+func (s *Server) initiateReplicaSet(ctx context.Context, client *mongo.Client) error {
+	admin := client.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{{Key: "replSetInitiate", Value: bson.M{
+		"_id":     s.replicaSet,
+		"members": bson.A{bson.M{"_id": 0, "host": fmt.Sprintf("127.0.0.1:%d", s.port)}},
+	}}}).Err()
+	if err != nil {
+		return fmt.Errorf("can't initiate replica set: %w", err)
+	}
+
+	for {
+		var res struct {
+			IsWritablePrimary bool `bson:"isWritablePrimary"`
+			IsMaster          bool `bson:"ismaster"`
+		}
+		err := admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res)
+		if err == nil && (res.IsWritablePrimary || res.IsMaster) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("replica set has no primary: %w", ctx.Err())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+var invalidDBChars = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// databaseName makes database name from test name, it's limited by 63 bytes
+func databaseName(testName string) string {
+	name := invalidDBChars.ReplaceAllString(testName, "_")
+	suffix := "_" + strconv.FormatInt(time.Now().UnixNano()%1e6, 36)
+	if len(name)+len(suffix) > 63 {
+		name = name[:63-len(suffix)]
+	}
+	return name + suffix
+}
+
+// tailBuffer keeps last size bytes written to it
+type tailBuffer struct {
+	mx   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.size {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.size:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,57 @@
+package mongotest
+
+import (
+	"context"
+	"errors"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	srv := RunServer(t, WithReplicaSet())
+	client := srv.Client(t)
+	require.Equal(t, mongodb.TopologyReplicaSet, client.Topology())
+
+	err := client.WithTX(ctx, func(ctx context.Context) error {
+		require.NoError(t, client.Create(ctx, &testItem{Name: "bad"}))
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	arr := []*testItem{}
+	require.NoError(t, client.Find(ctx, &arr, nil))
+	require.Empty(t, arr)
+}
+
+func TestDatabaseName(t *testing.T) {
+	name := databaseName("TestServer/sub test." + strings.Repeat("x", 100))
+	require.Len(t, name, 63)
+	require.True(t, strings.HasPrefix(name, "TestServer_sub_test_x"))
+}
+
+func TestStartServer_NotFound(t *testing.T) {
+	_, err := StartServer(WithBinary("/nonexistent/mongod"))
+	require.ErrorIs(t, err, errServerNotFound)
+}
+
+func TestStartServer_Output(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "mongod")
+	require.NoError(t, ioutil.WriteFile(binary, []byte("#!/bin/sh\necho 'Invalid command: --port'\nexit 2\n"), 0o755))
+
+	_, err := StartServer(WithBinary(binary), WithStartTimeout(200*time.Millisecond))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "mongod output:\nInvalid command: --port")
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{size: 4}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("def"))
+	require.Equal(t, "cdef", b.String())
+}
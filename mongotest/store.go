@@ -0,0 +1,132 @@
+package mongotest
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"sync"
+)
+
+type namespace struct {
+	database   string
+	collection string
+}
+
+func (n namespace) String() string {
+	return n.database + "." + n.collection
+}
+
+// document keeps BSON to decode models and its map to evaluate filters
+type document struct {
+	raw bson.Raw
+	doc bson.M
+}
+
+func newDocument(d bson.D) (document, error) {
+	raw, err := bson.Marshal(d)
+	if err != nil {
+		return document{}, err
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return document{}, err
+	}
+	return document{raw: raw, doc: doc}, nil
+}
+
+// store holds documents by namespace, callers lock it
+type store struct {
+	sync.Mutex
+	data map[namespace][]document
+}
+
+func newStore() *store {
+	return &store{data: make(map[namespace][]document)}
+}
+
+func (s *store) insert(ns namespace, d bson.D) error {
+	doc, err := newDocument(d)
+	if err != nil {
+		return err
+	}
+
+	s.data[ns] = append(s.data[ns], doc)
+	return nil
+}
+
+// set replaces fields of document like $set does
+func (s *store) set(ns namespace, i int, set bson.D) error {
+	var d bson.D
+	if err := bson.Unmarshal(s.data[ns][i].raw, &d); err != nil {
+		return err
+	}
+
+	for _, e := range set {
+		replaced := false
+		for j := range d {
+			if d[j].Key == e.Key {
+				d[j].Value = e.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			d = append(d, e)
+		}
+	}
+
+	doc, err := newDocument(d)
+	if err != nil {
+		return err
+	}
+	s.data[ns][i] = doc
+	return nil
+}
+
+func (s *store) remove(ns namespace, i int) {
+	docs := s.data[ns]
+	s.data[ns] = append(docs[:i:i], docs[i+1:]...)
+}
+
+func (s *store) findByID(ns namespace, id interface{}) (int, bool) {
+	filter, err := normalize(bson.M{"_id": id})
+	if err != nil {
+		return 0, false
+	}
+
+	for i, doc := range s.data[ns] {
+		if equal(doc.doc["_id"], filter["_id"]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (s *store) documents(ns namespace) []bson.M {
+	docs := make([]bson.M, 0, len(s.data[ns]))
+	for _, doc := range s.data[ns] {
+		docs = append(docs, doc.doc)
+	}
+	return docs
+}
+
+func (s *store) raw(ns namespace, i int) bson.Raw {
+	return s.data[ns][i].raw
+}
+
+// snapshot copies documents lists, documents themselves are never modified
+func (s *store) snapshot() map[namespace][]document {
+	s.Lock()
+	defer s.Unlock()
+
+	res := make(map[namespace][]document, len(s.data))
+	for ns, docs := range s.data {
+		res[ns] = append([]document(nil), docs...)
+	}
+	return res
+}
+
+func (s *store) restore(data map[namespace][]document) {
+	s.Lock()
+	defer s.Unlock()
+	s.data = data
+}
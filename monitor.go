@@ -0,0 +1,250 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const redacted = "?"
+
+// QueryEvent is a finished database command
+type QueryEvent struct {
+	Command    string
+	Database   string
+	Collection string
+	// Model is a type of model passed to client's method
+	Model string
+	// Filter is a query filter with redacted values
+	Filter   bson.M
+	Duration time.Duration
+	// Failure is an error message for failed command
+	Failure string
+}
+
+type modelKey struct{}
+
+// withModel stores model type in context for command monitor
+func withModel(ctx context.Context, rec interface{}) context.Context {
+	return context.WithValue(ctx, modelKey{}, modelName(rec))
+}
+
+func modelFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(modelKey{}).(string)
+	return name
+}
+
+func modelName(rec interface{}) string {
+	t := reflect.TypeOf(rec)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
+
+// queryMonitor logs slow commands and passes finished commands to callback
+type queryMonitor struct {
+	threshold time.Duration
+	callback  func(QueryEvent)
+	logger    logger.Logger
+
+	mx      sync.Mutex
+	started map[int64]QueryEvent
+}
+
+func newQueryMonitor(config *Config) *queryMonitor {
+	if config.SlowQuery <= 0 && config.OnQuery == nil {
+		return nil
+	}
+
+	return &queryMonitor{
+		threshold: config.SlowQuery,
+		callback:  config.OnQuery,
+		logger:    logger.Nop(),
+		started:   make(map[int64]QueryEvent),
+	}
+}
+
+func (m *queryMonitor) commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: m.onStarted,
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			m.onFinished(ctx, e.CommandFinishedEvent, "")
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			m.onFinished(ctx, e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+func (m *queryMonitor) onStarted(ctx context.Context, e *event.CommandStartedEvent) {
+	query := QueryEvent{
+		Command:  e.CommandName,
+		Database: e.DatabaseName,
+		Model:    modelFromContext(ctx),
+		Filter:   redactFilter(commandFilter(e.CommandName, e.Command)),
+	}
+	if coll, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+		query.Collection = coll
+	}
+
+	m.mx.Lock()
+	m.started[e.RequestID] = query
+	m.mx.Unlock()
+}
+
+func (m *queryMonitor) onFinished(ctx context.Context, e event.CommandFinishedEvent, failure string) {
+	m.mx.Lock()
+	query, ok := m.started[e.RequestID]
+	delete(m.started, e.RequestID)
+	m.mx.Unlock()
+	if !ok {
+		return
+	}
+
+	query.Duration = time.Duration(e.DurationNanos)
+	query.Failure = failure
+
+	if m.threshold > 0 && query.Duration >= m.threshold {
+		logger.FromContext(ctx, m.logger).Warn("slow query",
+			"command", query.Command,
+			"database", query.Database,
+			"collection", query.Collection,
+			"model", query.Model,
+			"filter", fmt.Sprint(query.Filter),
+			"duration", query.Duration.String(),
+		)
+	}
+	if m.callback != nil {
+		m.callback(query)
+	}
+}
+
+// chainMonitors returns monitor calling first and then second monitor's callbacks
+func chainMonitors(first, second *event.CommandMonitor) *event.CommandMonitor {
+	if first == nil {
+		return second
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if first.Started != nil {
+				first.Started(ctx, e)
+			}
+			if second.Started != nil {
+				second.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			if first.Succeeded != nil {
+				first.Succeeded(ctx, e)
+			}
+			if second.Succeeded != nil {
+				second.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			if first.Failed != nil {
+				first.Failed(ctx, e)
+			}
+			if second.Failed != nil {
+				second.Failed(ctx, e)
+			}
+		},
+	}
+}
+
+// commandFilter returns query filter of command
+func commandFilter(name string, cmd bson.Raw) bson.Raw {
+	var val bson.RawValue
+	switch name {
+	case "find", "distinct":
+		val = cmd.Lookup("filter")
+	case "count", "findAndModify":
+		val = cmd.Lookup("query")
+	case "delete":
+		val = cmd.Lookup("deletes", "0", "q")
+	case "update":
+		val = cmd.Lookup("updates", "0", "q")
+	case "aggregate":
+		// CountDocuments sends aggregate with $match stage
+		val = firstMatch(cmd.Lookup("pipeline"))
+	default:
+		return nil
+	}
+
+	doc, _ := val.DocumentOK()
+	return doc
+}
+
+// firstMatch returns filter of first $match stage of pipeline
+func firstMatch(pipeline bson.RawValue) bson.RawValue {
+	stages, ok := pipeline.ArrayOK()
+	if !ok {
+		return bson.RawValue{}
+	}
+	values, err := stages.Values()
+	if err != nil {
+		return bson.RawValue{}
+	}
+	for _, stage := range values {
+		doc, ok := stage.DocumentOK()
+		if !ok {
+			continue
+		}
+		if match, err := doc.LookupErr("$match"); err == nil {
+			return match
+		}
+	}
+	return bson.RawValue{}
+}
+
+// redactFilter replaces values in filter, keeping fields and operators
+func redactFilter(filter bson.Raw) bson.M {
+	if filter == nil {
+		return nil
+	}
+
+	elems, err := filter.Elements()
+	if err != nil {
+		return nil
+	}
+
+	res := bson.M{}
+	for _, el := range elems {
+		res[el.Key()] = redactValue(el.Key(), el.Value())
+	}
+	return res
+}
+
+func redactValue(key string, val bson.RawValue) interface{} {
+	switch val.Type {
+	case bsontype.EmbeddedDocument:
+		return redactFilter(val.Document())
+	case bsontype.Array:
+		if key != "$and" && key != "$or" && key != "$nor" {
+			return redacted
+		}
+
+		values, _ := val.Array().Values()
+		res := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			res = append(res, redactValue("", v))
+		}
+		return res
+	default:
+		if options, ok := val.StringValueOK(); ok && key == "$options" {
+			return options
+		}
+		return redacted
+	}
+}
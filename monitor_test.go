@@ -0,0 +1,105 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+	"testing"
+	"time"
+)
+
+type monitorItem struct {
+	collection struct{} `bson:"books"`
+}
+
+func TestRedactFilter(t *testing.T) {
+	filter := opt.GetFilter(
+		opt.Or(opt.Eq("name", "secret"), opt.Contains("description", "word")),
+		opt.In("value", []int{1, 2}),
+	)
+	raw, err := bson.Marshal(filter)
+	require.NoError(t, err)
+
+	require.Equal(t, bson.M{
+		"$or": []interface{}{
+			bson.M{"name": bson.M{"$eq": "?"}},
+			bson.M{"description": bson.M{"$regex": "?", "$options": "i"}},
+		},
+		"value": bson.M{"$in": "?"},
+	}, redactFilter(raw))
+
+	raw, err = bson.Marshal(bson.M{"name": bson.M{"$regex": "a", "$options": 1}})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"name": bson.M{"$regex": "?", "$options": "?"}}, redactFilter(raw))
+}
+
+func TestQueryMonitor(t *testing.T) {
+	var events []QueryEvent
+	mon := newQueryMonitor(&Config{OnQuery: func(e QueryEvent) {
+		events = append(events, e)
+	}})
+	cm := mon.commandMonitor()
+
+	cmd, err := bson.Marshal(bson.D{
+		{Key: "find", Value: "books"},
+		{Key: "filter", Value: bson.M{"name": "secret"}},
+	})
+	require.NoError(t, err)
+
+	ctx := withModel(context.Background(), &[]*monitorItem{})
+	cm.Started(ctx, &event.CommandStartedEvent{Command: cmd, DatabaseName: "test", CommandName: "find", RequestID: 1})
+	cm.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 1, DurationNanos: int64(time.Second)},
+		Failure:              "boom",
+	})
+
+	require.Equal(t, []QueryEvent{{
+		Command:    "find",
+		Database:   "test",
+		Collection: "books",
+		Model:      "mongodb.monitorItem",
+		Filter:     bson.M{"name": "?"},
+		Duration:   time.Second,
+		Failure:    "boom",
+	}}, events)
+	require.Empty(t, mon.started)
+	require.Nil(t, newQueryMonitor(&Config{}))
+}
+
+func TestCommandFilter(t *testing.T) {
+	cmd, err := bson.Marshal(bson.D{
+		{Key: "aggregate", Value: "books"},
+		{Key: "pipeline", Value: bson.A{
+			bson.M{"$geoNear": bson.M{"near": bson.M{"type": "Point"}}},
+			bson.M{"$match": bson.M{"name": "secret"}},
+			bson.M{"$group": bson.M{"_id": 1, "n": bson.M{"$sum": 1}}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, bson.M{"name": "?"}, redactFilter(commandFilter("aggregate", cmd)))
+
+	cmd, err = bson.Marshal(bson.D{{Key: "aggregate", Value: "books"}, {Key: "pipeline", Value: bson.A{}}})
+	require.NoError(t, err)
+	require.Nil(t, commandFilter("aggregate", cmd))
+}
+
+func TestChainMonitors(t *testing.T) {
+	var calls []string
+	user := &event.CommandMonitor{
+		Started: func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "user started") },
+	}
+	own := &event.CommandMonitor{
+		Started:   func(context.Context, *event.CommandStartedEvent) { calls = append(calls, "own started") },
+		Succeeded: func(context.Context, *event.CommandSucceededEvent) { calls = append(calls, "own succeeded") },
+	}
+
+	require.Equal(t, own, chainMonitors(nil, own))
+
+	mon := chainMonitors(user, own)
+	mon.Started(context.Background(), &event.CommandStartedEvent{})
+	mon.Succeeded(context.Background(), &event.CommandSucceededEvent{})
+	mon.Failed(context.Background(), &event.CommandFailedEvent{})
+	require.Equal(t, []string{"user started", "own started", "own succeeded"}, calls)
+}
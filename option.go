@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type OptionFn func(w *dbWrapper)
+
+// WithTopology sets deployment topology without server detection
+func WithTopology(topology Topology) OptionFn {
+	return func(w *dbWrapper) {
+		w.topology = topology
+	}
+}
+
+// WithTransactions forces transactions support regardless of topology
+func WithTransactions(enabled bool) OptionFn {
+	return func(w *dbWrapper) {
+		w.txEnabled = &enabled
+	}
+}
+
+// WithTxRetry sets how long transaction is retried on transient errors and pause between attempts,
+// zero maxDuration disables retries
+func WithTxRetry(maxDuration, backoff time.Duration) OptionFn {
+	return func(w *dbWrapper) {
+		w.txRetry = txRetry{maxDuration: maxDuration, backoff: backoff}
+	}
+}
+
+// WithLogger sets logger, it could be overridden for request by logger.ToContext,
+// nil disables logging
+func WithLogger(l logger.Logger) OptionFn {
+	return func(w *dbWrapper) {
+		if l == nil {
+			l = logger.Nop()
+		}
+		w.logger = l
+	}
+}
+
+// WithHook adds hook called around client's operations
+func WithHook(h Hook) OptionFn {
+	return func(w *dbWrapper) {
+		w.hooks = append(w.hooks, h)
+	}
+}
+
+// WithClientOptions adds driver's client options used by Connect, e.g. pool monitor
+func WithClientOptions(opts ...*options.ClientOptions) OptionFn {
+	return func(w *dbWrapper) {
+		w.clientOpts = append(w.clientOpts, opts...)
+	}
+}
+
+// WithStrictMode validates fields and values of filter and sorting against model before query is sent,
+// see ValidateOptions
+func WithStrictMode() OptionFn {
+	return func(w *dbWrapper) {
+		w.strict = true
+	}
+}
+
+// WithCollScanGuard explains Find, FindOne and Count queries on collections before they are sent and logs
+// collection scans or fails with ErrCollScan, all collections are checked when none given.
+// It doubles number of queries, so it's meant for development and tests
+func WithCollScanGuard(fail bool, collections ...string) OptionFn {
+	return func(w *dbWrapper) {
+		g := &collScanGuard{fail: fail, collections: make(map[string]bool, len(collections))}
+		for _, name := range collections {
+			g.collections[name] = true
+		}
+		w.collScan = g
+	}
+}
@@ -2,6 +2,8 @@ package filter
 
 import (
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
 )
 
 // Condition common facade for filter conditions
@@ -33,9 +35,12 @@ type In map[string][]interface{}
 // NotIn field name not contains in list of values
 type NotIn map[string][]interface{}
 
-// Match filter
+// Match field name matches regular expression (case sensitive)
 type Match map[string]string
 
+// Regex field name matches regular expression with options
+type Regex map[string]primitive.Regex
+
 // Contains filter
 type Contains map[string]string
 
@@ -45,13 +50,45 @@ type IsNull string
 // NotNull field name not equal to NULL
 type NotNull string
 
+// Exists field name exists or not
+type Exists map[string]bool
+
+// Type field name has one of BSON types, e.g. "string" or "int"
+type Type map[string][]string
+
+// All field name array contains all values
+type All map[string][]interface{}
+
+// Size field name array has length
+type Size map[string]int
+
+// ElemMatch field name array has element matching all conditions,
+// conditions with empty field name apply to element itself, repeated operators on element
+// are folded: bounds are narrowed, $in is intersected, $nin and $ne are united
+type ElemMatch map[string]Filter
+
+// Mod field name divided by divisor has remainder
+type Mod map[string][2]int64
+
+// Expr aggregation expression
+type Expr bson.M
+
+// Text full-text search by collection's text index
+type Text struct {
+	Search        string
+	Language      string
+	CaseSensitive bool
+}
+
 // Or filter
 type Or []Condition
 
 // And filter
 type And []Condition
 
-// Not filter
+// Not negates conjunction of conditions, condition on single field
+// is negated with field-level $not, otherwise $nor is used,
+// Text, Near and NearSphere can't be negated, client rejects such filters
 type Not []Condition
 
 func (c Eq) Condition() bson.M {
@@ -117,6 +154,92 @@ func (c Contains) Condition() bson.M {
 	return nil
 }
 
+func (c Match) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$regex": val}}
+	}
+	return nil
+}
+
+func (c Regex) Condition() bson.M {
+	for key, val := range c {
+		if val.Options == "" {
+			return bson.M{key: bson.M{"$regex": val.Pattern}}
+		}
+		return bson.M{key: bson.M{"$regex": val.Pattern, "$options": val.Options}}
+	}
+	return nil
+}
+
+func (c Exists) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$exists": val}}
+	}
+	return nil
+}
+
+func (c Type) Condition() bson.M {
+	for key, val := range c {
+		if len(val) == 1 {
+			return bson.M{key: bson.M{"$type": val[0]}}
+		}
+		return bson.M{key: bson.M{"$type": val}}
+	}
+	return nil
+}
+
+func (c All) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$all": val}}
+	}
+	return nil
+}
+
+func (c Size) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$size": val}}
+	}
+	return nil
+}
+
+func (c ElemMatch) Condition() bson.M {
+	for key, val := range c {
+		query := And(val).Condition()
+		// conditions on empty field are applied to element itself
+		if ops, ok := operators(query[""]); ok {
+			delete(query, "")
+			ops = foldElementConditions(query, ops)
+			for op, arg := range ops {
+				query[op] = arg
+			}
+		}
+		return bson.M{key: bson.M{"$elemMatch": query}}
+	}
+	return nil
+}
+
+func (c Mod) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$mod": []int64{val[0], val[1]}}}
+	}
+	return nil
+}
+
+func (c Expr) Condition() bson.M {
+	return bson.M{"$expr": bson.M(c)}
+}
+
+func (c Text) Condition() bson.M {
+	query := bson.M{"$search": c.Search}
+	if c.Language != "" {
+		query["$language"] = c.Language
+	}
+	if c.CaseSensitive {
+		query["$caseSensitive"] = true
+	}
+	return bson.M{"$text": query}
+}
+
 func (c IsNull) Condition() bson.M {
 	return bson.M{string(c): bson.M{"$eq": nil}}
 }
@@ -126,25 +249,70 @@ func (c NotNull) Condition() bson.M {
 }
 
 func (c Or) Condition() bson.M {
-	var result []interface{}
-	for _, cond := range c {
-		result = append(result, cond.Condition())
-	}
-	return bson.M{"$or": result}
+	return Normalize(bson.M{"$or": conditions(c)})
 }
 
 func (c And) Condition() bson.M {
-	var result []interface{}
-	for _, cond := range c {
-		result = append(result, cond.Condition())
-	}
-	return bson.M{"$and": result}
+	return Normalize(bson.M{"$and": conditions(c)})
 }
 
 func (c Not) Condition() bson.M {
-	var result []interface{}
+	query := And(c).Condition()
+	if len(query) == 0 {
+		return query
+	}
+	if len(query) == 1 {
+		for key, val := range query {
+			if ops, ok := operators(val); ok && !strings.HasPrefix(key, "$") {
+				return bson.M{key: bson.M{"$not": ops}}
+			}
+		}
+	}
+	return bson.M{"$nor": []interface{}{query}}
+}
+
+// foldElementConditions moves repeated element level operators from $and of query to ops,
+// operators that can't be folded, e.g. two different $regex, are left in $and and rejected by server
+func foldElementConditions(query, ops bson.M) bson.M {
+	list, _ := toList(query["$and"])
+	folded := make(bson.M, len(ops))
+	for op, arg := range ops {
+		folded[op] = arg
+	}
+
+	var rest []interface{}
+	for _, item := range list {
+		if m, ok := item.(bson.M); ok && len(m) == 1 {
+			if itemOps, ok := operators(m[""]); ok {
+				applied := true
+				for _, op := range sortedKeys(itemOps) {
+					if !foldElementOps(folded, op, itemOps[op]) {
+						applied = false
+					}
+				}
+				if applied {
+					continue
+				}
+			}
+		}
+		rest = append(rest, item)
+	}
+
+	if len(rest) > 0 {
+		query["$and"] = rest
+	} else {
+		delete(query, "$and")
+	}
+	return folded
+}
+
+// conditions returns queries of conditions, empty conditions like And() are dropped
+func conditions(c []Condition) []interface{} {
+	result := make([]interface{}, 0, len(c))
 	for _, cond := range c {
-		result = append(result, cond.Condition())
+		if query := cond.Condition(); len(query) > 0 {
+			result = append(result, query)
+		}
 	}
-	return bson.M{"$not": result}
+	return result
 }
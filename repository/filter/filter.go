@@ -6,10 +6,7 @@ import (
 
 type Filter []Condition
 
+// Apply returns normalized query joining conditions with AND
 func (f Filter) Apply() bson.M {
-	var result []interface{}
-	for _, cond := range f {
-		result = append(result, cond.Condition())
-	}
-	return bson.M{"$and": result}
+	return And(f).Condition()
 }
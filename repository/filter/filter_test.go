@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	cases := []struct {
+		name   string
+		filter Filter
+		query  bson.M
+	}{
+		{
+			name:   "single condition",
+			filter: Filter{Eq{"name": "hello"}},
+			query:  bson.M{"name": bson.M{"$eq": "hello"}},
+		},
+		{
+			name:   "same field merged",
+			filter: Filter{Ge{"value": 1}, Lt{"value": 5}, Eq{"name": "hello"}},
+			query: bson.M{
+				"value": bson.M{"$gte": 1, "$lt": 5},
+				"name":  bson.M{"$eq": "hello"},
+			},
+		},
+		{
+			name:   "same operator kept in and",
+			filter: Filter{Gt{"value": 1}, Gt{"value": 5}},
+			query: bson.M{
+				"value": bson.M{"$gt": 1},
+				"$and":  []interface{}{bson.M{"value": bson.M{"$gt": 5}}},
+			},
+		},
+		{
+			name:   "nested and flattened",
+			filter: Filter{And{Eq{"name": "hello"}, And{Gt{"value": 1}}}},
+			query: bson.M{
+				"name":  bson.M{"$eq": "hello"},
+				"value": bson.M{"$gt": 1},
+			},
+		},
+		{
+			name:   "nested or flattened",
+			filter: Filter{Or{Eq{"name": "a"}, Or{Eq{"name": "b"}, Eq{"name": "c"}}}},
+			query: bson.M{"$or": []interface{}{
+				bson.M{"name": bson.M{"$eq": "a"}},
+				bson.M{"name": bson.M{"$eq": "b"}},
+				bson.M{"name": bson.M{"$eq": "c"}},
+			}},
+		},
+		{
+			name:   "single or unwrapped",
+			filter: Filter{Or{Eq{"name": "a"}}, Gt{"value": 1}},
+			query: bson.M{
+				"name":  bson.M{"$eq": "a"},
+				"value": bson.M{"$gt": 1},
+			},
+		},
+		{
+			name:   "two or kept in and",
+			filter: Filter{Or{Eq{"a": 1}, Eq{"b": 1}}, Or{Eq{"c": 1}, Eq{"d": 1}}},
+			query: bson.M{
+				"$or": []interface{}{bson.M{"a": bson.M{"$eq": 1}}, bson.M{"b": bson.M{"$eq": 1}}},
+				"$and": []interface{}{bson.M{"$or": []interface{}{
+					bson.M{"c": bson.M{"$eq": 1}},
+					bson.M{"d": bson.M{"$eq": 1}},
+				}}},
+			},
+		},
+		{
+			name:   "not on field",
+			filter: Filter{Not{Gt{"value": 1}, Lt{"value": 5}}},
+			query:  bson.M{"value": bson.M{"$not": bson.M{"$gt": 1, "$lt": 5}}},
+		},
+		{
+			name:   "not on several fields",
+			filter: Filter{Not{Eq{"name": "hello"}, Gt{"value": 1}}},
+			query: bson.M{"$nor": []interface{}{bson.M{
+				"name":  bson.M{"$eq": "hello"},
+				"value": bson.M{"$gt": 1},
+			}}},
+		},
+		{
+			name:   "not of or",
+			filter: Filter{Not{Or{Eq{"a": 1}, Eq{"b": 1}}}},
+			query: bson.M{"$nor": []interface{}{bson.M{"$or": []interface{}{
+				bson.M{"a": bson.M{"$eq": 1}},
+				bson.M{"b": bson.M{"$eq": 1}},
+			}}}},
+		},
+		{
+			name:   "expressions kept in and",
+			filter: Filter{Expr{"$gt": bson.A{"$a", 1}}, Expr{"$lt": bson.A{"$a", 5}}},
+			query: bson.M{
+				"$expr": bson.M{"$gt": bson.A{"$a", 1}},
+				"$and":  []interface{}{bson.M{"$expr": bson.M{"$lt": bson.A{"$a", 5}}}},
+			},
+		},
+		{
+			name:   "empty conditions dropped",
+			filter: Filter{Or{}, And{}, Not{}, Or{And{}}, Eq{"name": "hello"}},
+			query:  bson.M{"name": bson.M{"$eq": "hello"}},
+		},
+		{
+			name:   "empty or",
+			filter: Filter{Or{}},
+			query:  bson.M{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.query, c.filter.Apply())
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	query := bson.M{
+		"$and": bson.A{
+			bson.M{"value": bson.M{"$gt": 1}},
+			bson.M{"$and": []bson.M{{"value": bson.M{"$lt": 5}}}},
+		},
+		"$or":  []interface{}{bson.M{"$or": []interface{}{bson.M{"a": 1}, bson.M{"b": 1}}}},
+		"name": "hello",
+	}
+
+	expected := bson.M{
+		"value": bson.M{"$gt": 1, "$lt": 5},
+		"$or":   []interface{}{bson.M{"a": 1}, bson.M{"b": 1}},
+		"name":  "hello",
+	}
+	require.Equal(t, expected, Normalize(query))
+	require.Equal(t, expected, Normalize(expected))
+
+	queries := []bson.M{
+		{"value": bson.M{"$gt": 1}, "$and": []interface{}{bson.M{"value": bson.M{"$gt": 5}}}},
+		{"$and": []interface{}{bson.M{"value": bson.M{"$gt": 1}}, bson.M{"value": bson.M{"$gt": 5}}}},
+		{"$expr": bson.M{"$gt": bson.A{"$a", 1}}, "$and": []interface{}{bson.M{"$expr": bson.M{"$lt": bson.A{"$a", 5}}}}},
+		{"value": bson.M{"$gt": 1}, "$or": []interface{}{bson.M{"value": bson.M{"$gt": 5}}}},
+	}
+	for _, q := range queries {
+		once := Normalize(q)
+		require.Equal(t, once, Normalize(once))
+	}
+	require.Equal(t, queries[0], Normalize(queries[1]))
+}
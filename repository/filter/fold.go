@@ -0,0 +1,135 @@
+package filter
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// foldElementOps adds element level operators of $elemMatch to ops, there is no field name to keep
+// repeated operator in $and, so bounds are narrowed, $in is intersected and $nin is united,
+// false is returned when operator can't be folded
+func foldElementOps(ops bson.M, op string, arg interface{}) bool {
+	prev, exists := ops[op]
+	if !exists {
+		ops[op] = arg
+		return true
+	}
+
+	switch op {
+	case "$gt", "$gte", "$lt", "$lte":
+		c, ok := compareValues(prev, arg)
+		if !ok {
+			return false
+		}
+		if strings.HasPrefix(op, "$g") && c < 0 || strings.HasPrefix(op, "$l") && c > 0 {
+			ops[op] = arg
+		}
+		return true
+	case "$in":
+		prevItems, ok := toValues(prev)
+		items, ok2 := toValues(arg)
+		if !ok || !ok2 {
+			return false
+		}
+		in := []interface{}{}
+		for _, item := range prevItems {
+			if containsValue(items, item) {
+				in = append(in, item)
+			}
+		}
+		ops[op] = in
+		return true
+	case "$nin":
+		prevItems, ok := toValues(prev)
+		items, ok2 := toValues(arg)
+		if !ok || !ok2 {
+			return false
+		}
+		for _, item := range items {
+			if !containsValue(prevItems, item) {
+				prevItems = append(prevItems, item)
+			}
+		}
+		ops[op] = prevItems
+		return true
+	case "$ne":
+		if _, exists := ops["$nin"]; exists {
+			if !foldElementOps(ops, "$nin", []interface{}{prev, arg}) {
+				return false
+			}
+		} else {
+			ops["$nin"] = []interface{}{prev, arg}
+		}
+		delete(ops, "$ne")
+		return true
+	}
+	return reflect.DeepEqual(prev, arg)
+}
+
+// compareValues compares numbers, strings and times
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return compare(x < y, x > y), true
+		}
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return compare(x.Before(y), x.After(y)), true
+		}
+	}
+	return 0, false
+}
+
+func compare(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func toValues(val interface{}) ([]interface{}, bool) {
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	res := make([]interface{}, v.Len())
+	for i := range res {
+		res[i] = v.Index(i).Interface()
+	}
+	return res, true
+}
+
+func containsValue(items []interface{}, val interface{}) bool {
+	for _, item := range items {
+		if reflect.DeepEqual(item, val) {
+			return true
+		}
+	}
+	return false
+}
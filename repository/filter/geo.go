@@ -0,0 +1,93 @@
+package filter
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Near field name geometry is near point, sorted from nearest to farthest
+type Near map[string]Proximity
+
+// NearSphere field name geometry is near point on sphere, sorted from nearest to farthest
+type NearSphere map[string]Proximity
+
+// GeoWithin field name geometry is within shape: model.Polygon, Box or CenterSphere
+type GeoWithin map[string]interface{}
+
+// GeoIntersects field name geometry intersects with geometry
+type GeoIntersects map[string]model.Geometry
+
+// Proximity is a point with distance bounds in meters, zero bound is not applied
+type Proximity struct {
+	Point       model.Point
+	MaxDistance float64
+	MinDistance float64
+}
+
+// Box is a rectangle by bottom left and top right [lng, lat] corners, it's queried as GeoJSON polygon
+// to work with 2dsphere index, so its edges are geodesic lines rather than parallels
+type Box struct {
+	BottomLeft [2]float64 `json:"bottomLeft"`
+	TopRight   [2]float64 `json:"topRight"`
+}
+
+// CenterSphere is a circle on sphere by [lng, lat] center and radius in radians
+type CenterSphere struct {
+	Center [2]float64 `json:"center"`
+	Radius float64    `json:"radius"`
+}
+
+func (c Near) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$near": val.query()}}
+	}
+	return nil
+}
+
+func (c NearSphere) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$nearSphere": val.query()}}
+	}
+	return nil
+}
+
+func (c GeoWithin) Condition() bson.M {
+	for key, val := range c {
+		var shape bson.M
+		switch v := val.(type) {
+		case Box:
+			shape = bson.M{"$geometry": v.polygon()}
+		case CenterSphere:
+			shape = bson.M{"$centerSphere": []interface{}{v.Center, v.Radius}}
+		default:
+			shape = bson.M{"$geometry": v}
+		}
+		return bson.M{key: bson.M{"$geoWithin": shape}}
+	}
+	return nil
+}
+
+func (c GeoIntersects) Condition() bson.M {
+	for key, val := range c {
+		return bson.M{key: bson.M{"$geoIntersects": bson.M{"$geometry": val}}}
+	}
+	return nil
+}
+
+func (p Proximity) query() bson.M {
+	query := bson.M{"$geometry": p.Point}
+	if p.MaxDistance > 0 {
+		query["$maxDistance"] = p.MaxDistance
+	}
+	if p.MinDistance > 0 {
+		query["$minDistance"] = p.MinDistance
+	}
+	return query
+}
+
+func (b Box) polygon() model.Polygon {
+	bl, tr := b.BottomLeft, b.TopRight
+	return model.NewPolygon([][]float64{
+		{bl[0], bl[1]}, {tr[0], bl[1]}, {tr[0], tr[1]}, {bl[0], tr[1]}, {bl[0], bl[1]},
+	})
+}
@@ -0,0 +1,680 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+)
+
+// node is a JSON representation of condition, values are encoded as canonical extended JSON
+// to keep BSON types, e.g. {"op": "gt", "field": "value", "value": {"$numberInt": "5"}}
+type node struct {
+	Op         string          `json:"op"`
+	Field      string          `json:"field,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Conditions []node          `json:"conditions,omitempty"`
+}
+
+type regexValue struct {
+	Pattern string `json:"pattern"`
+	Options string `json:"options,omitempty"`
+}
+
+type textValue struct {
+	Search        string `json:"search"`
+	Language      string `json:"language,omitempty"`
+	CaseSensitive bool   `json:"caseSensitive,omitempty"`
+}
+
+type proximityValue struct {
+	Point       model.Point `json:"point"`
+	MaxDistance float64     `json:"maxDistance,omitempty"`
+	MinDistance float64     `json:"minDistance,omitempty"`
+}
+
+type shapeValue struct {
+	Box          *Box            `json:"box,omitempty"`
+	CenterSphere *CenterSphere   `json:"centerSphere,omitempty"`
+	Geometry     json.RawMessage `json:"geometry,omitempty"`
+}
+
+var errOneField = errors.New("condition must have one field")
+
+// MarshalJSON encodes filter as array of conditions
+func (f Filter) MarshalJSON() ([]byte, error) {
+	nodes, err := encodeConditions(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(nodes)
+}
+
+// UnmarshalJSON decodes filter encoded by MarshalJSON
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	var nodes []node
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return err
+	}
+	conds, err := decodeConditions(nodes)
+	if err != nil {
+		return err
+	}
+	*f = conds
+	return nil
+}
+
+// UnmarshalCondition decodes condition of any type encoded by its MarshalJSON
+func UnmarshalCondition(data []byte) (Condition, error) {
+	var n node
+	if err := json.Unmarshal(data, &n); err != nil {
+		return nil, err
+	}
+	return decodeCondition(n)
+}
+
+func marshalCondition(c Condition) ([]byte, error) {
+	n, err := encodeCondition(c)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+// unmarshalCondition decodes condition into pointer to condition of the same type
+func unmarshalCondition(data []byte, dst interface{}) error {
+	c, err := UnmarshalCondition(data)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dst).Elem()
+	if reflect.TypeOf(c) != v.Type() {
+		return fmt.Errorf("can't unmarshal %T condition into %s", c, v.Type())
+	}
+	v.Set(reflect.ValueOf(c))
+	return nil
+}
+
+func encodeConditions(conds []Condition) ([]node, error) {
+	nodes := make([]node, 0, len(conds))
+	for _, c := range conds {
+		n, err := encodeCondition(c)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+func encodeCondition(c Condition) (node, error) {
+	switch v := c.(type) {
+	case Eq:
+		return encodeValue("eq", v)
+	case Ne:
+		return encodeValue("ne", v)
+	case Lt:
+		return encodeValue("lt", v)
+	case Le:
+		return encodeValue("lte", v)
+	case Gt:
+		return encodeValue("gt", v)
+	case Ge:
+		return encodeValue("gte", v)
+	case In:
+		return encodeValue("in", v)
+	case NotIn:
+		return encodeValue("nin", v)
+	case All:
+		return encodeValue("all", v)
+	case Match:
+		return encodeJSON("match", v, nil)
+	case Contains:
+		return encodeJSON("contains", v, nil)
+	case Exists:
+		return encodeJSON("exists", v, nil)
+	case Type:
+		return encodeJSON("type", v, nil)
+	case Size:
+		return encodeJSON("size", v, nil)
+	case Mod:
+		return encodeJSON("mod", v, nil)
+	case Regex:
+		return encodeJSON("regex", v, func(val interface{}) interface{} {
+			r := val.(primitive.Regex)
+			return regexValue{Pattern: r.Pattern, Options: r.Options}
+		})
+	case Near:
+		return encodeJSON("near", v, newProximityValue)
+	case NearSphere:
+		return encodeJSON("nearSphere", v, newProximityValue)
+	case GeoIntersects:
+		return encodeJSON("geoIntersects", v, nil)
+	case GeoWithin:
+		return encodeJSON("geoWithin", v, func(val interface{}) interface{} {
+			switch shape := val.(type) {
+			case Box:
+				return shapeValue{Box: &shape}
+			case CenterSphere:
+				return shapeValue{CenterSphere: &shape}
+			}
+			geometry, _ := json.Marshal(val)
+			return shapeValue{Geometry: geometry}
+		})
+	case ElemMatch:
+		field, val, err := singleField(v)
+		if err != nil {
+			return node{}, err
+		}
+		nodes, err := encodeConditions(val.(Filter))
+		return node{Op: "elemMatch", Field: field, Conditions: nodes}, err
+	case IsNull:
+		return node{Op: "isNull", Field: string(v)}, nil
+	case NotNull:
+		return node{Op: "notNull", Field: string(v)}, nil
+	case Expr:
+		value, err := marshalValue(bson.M(v))
+		return node{Op: "expr", Value: value}, err
+	case Text:
+		value, err := json.Marshal(textValue{Search: v.Search, Language: v.Language, CaseSensitive: v.CaseSensitive})
+		return node{Op: "text", Value: value}, err
+	case And:
+		nodes, err := encodeConditions(v)
+		return node{Op: "and", Conditions: nodes}, err
+	case Or:
+		nodes, err := encodeConditions(v)
+		return node{Op: "or", Conditions: nodes}, err
+	case Not:
+		nodes, err := encodeConditions(v)
+		return node{Op: "not", Conditions: nodes}, err
+	}
+	return node{}, fmt.Errorf("can't marshal condition %T", c)
+}
+
+// encodeValue encodes condition with value of any type as extended JSON
+func encodeValue(op string, cond interface{}) (node, error) {
+	field, val, err := singleField(cond)
+	if err != nil {
+		return node{}, err
+	}
+	raw, err := marshalValue(val)
+	return node{Op: op, Field: field, Value: raw}, err
+}
+
+// encodeJSON encodes condition with typed value as JSON, value is converted by fn when it's set
+func encodeJSON(op string, cond interface{}, fn func(val interface{}) interface{}) (node, error) {
+	field, val, err := singleField(cond)
+	if err != nil {
+		return node{}, err
+	}
+	if fn != nil {
+		val = fn(val)
+	}
+	raw, err := json.Marshal(val)
+	return node{Op: op, Field: field, Value: raw}, err
+}
+
+// singleField returns field and value of map condition
+func singleField(cond interface{}) (string, interface{}, error) {
+	m := reflect.ValueOf(cond)
+	if m.Len() != 1 {
+		return "", nil, errOneField
+	}
+	key := m.MapKeys()[0]
+	return key.String(), m.MapIndex(key).Interface(), nil
+}
+
+func newProximityValue(val interface{}) interface{} {
+	p := val.(Proximity)
+	return proximityValue{Point: p.Point, MaxDistance: p.MaxDistance, MinDistance: p.MinDistance}
+}
+
+func decodeConditions(nodes []node) ([]Condition, error) {
+	conds := make([]Condition, 0, len(nodes))
+	for _, n := range nodes {
+		c, err := decodeCondition(n)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+func decodeCondition(n node) (Condition, error) {
+	switch n.Op {
+	case "eq", "ne", "lt", "lte", "gt", "gte":
+		val, err := unmarshalValue(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "eq":
+			return Eq{n.Field: val}, nil
+		case "ne":
+			return Ne{n.Field: val}, nil
+		case "lt":
+			return Lt{n.Field: val}, nil
+		case "lte":
+			return Le{n.Field: val}, nil
+		case "gt":
+			return Gt{n.Field: val}, nil
+		}
+		return Ge{n.Field: val}, nil
+	case "in", "nin", "all":
+		val, err := unmarshalValue(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		list, ok := val.(primitive.A)
+		if !ok && val != nil {
+			return nil, fmt.Errorf("%s condition needs array value", n.Op)
+		}
+		switch n.Op {
+		case "in":
+			return In{n.Field: list}, nil
+		case "nin":
+			return NotIn{n.Field: list}, nil
+		}
+		return All{n.Field: list}, nil
+	case "match", "contains":
+		var val string
+		if err := json.Unmarshal(n.Value, &val); err != nil {
+			return nil, err
+		}
+		if n.Op == "match" {
+			return Match{n.Field: val}, nil
+		}
+		return Contains{n.Field: val}, nil
+	case "regex":
+		var val regexValue
+		err := json.Unmarshal(n.Value, &val)
+		return Regex{n.Field: primitive.Regex{Pattern: val.Pattern, Options: val.Options}}, err
+	case "exists":
+		var val bool
+		err := json.Unmarshal(n.Value, &val)
+		return Exists{n.Field: val}, err
+	case "type":
+		var val []string
+		err := json.Unmarshal(n.Value, &val)
+		return Type{n.Field: val}, err
+	case "size":
+		var val int
+		err := json.Unmarshal(n.Value, &val)
+		return Size{n.Field: val}, err
+	case "mod":
+		var val [2]int64
+		err := json.Unmarshal(n.Value, &val)
+		return Mod{n.Field: val}, err
+	case "near", "nearSphere":
+		var val proximityValue
+		if err := json.Unmarshal(n.Value, &val); err != nil {
+			return nil, err
+		}
+		p := Proximity{Point: val.Point, MaxDistance: val.MaxDistance, MinDistance: val.MinDistance}
+		if n.Op == "near" {
+			return Near{n.Field: p}, nil
+		}
+		return NearSphere{n.Field: p}, nil
+	case "geoWithin":
+		var val shapeValue
+		if err := json.Unmarshal(n.Value, &val); err != nil {
+			return nil, err
+		}
+		switch {
+		case val.Box != nil:
+			return GeoWithin{n.Field: *val.Box}, nil
+		case val.CenterSphere != nil:
+			return GeoWithin{n.Field: *val.CenterSphere}, nil
+		}
+		geometry, err := unmarshalGeometry(val.Geometry)
+		return GeoWithin{n.Field: geometry}, err
+	case "geoIntersects":
+		geometry, err := unmarshalGeometry(n.Value)
+		return GeoIntersects{n.Field: geometry}, err
+	case "elemMatch":
+		conds, err := decodeConditions(n.Conditions)
+		return ElemMatch{n.Field: conds}, err
+	case "isNull":
+		return IsNull(n.Field), nil
+	case "notNull":
+		return NotNull(n.Field), nil
+	case "expr":
+		val, err := unmarshalValue(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		expr, ok := val.(primitive.M)
+		if !ok {
+			return nil, errors.New("expr condition needs document value")
+		}
+		return Expr(expr), nil
+	case "text":
+		var val textValue
+		err := json.Unmarshal(n.Value, &val)
+		return Text{Search: val.Search, Language: val.Language, CaseSensitive: val.CaseSensitive}, err
+	case "and", "or", "not":
+		conds, err := decodeConditions(n.Conditions)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "and":
+			return And(conds), nil
+		case "or":
+			return Or(conds), nil
+		}
+		return Not(conds), nil
+	}
+	return nil, fmt.Errorf("unknown condition %q", n.Op)
+}
+
+// marshalValue encodes value as canonical extended JSON, documents keys are sorted
+func marshalValue(val interface{}) (json.RawMessage, error) {
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: sortKeys(val)}}, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		V json.RawMessage `json:"v"`
+	}
+	err = json.Unmarshal(data, &doc)
+	return doc.V, err
+}
+
+func unmarshalValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(map[string]json.RawMessage{"v": raw})
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.UnmarshalExtJSON(data, true, &doc); err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+func unmarshalGeometry(raw json.RawMessage) (model.Geometry, error) {
+	var geo struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &geo); err != nil {
+		return nil, err
+	}
+
+	var res model.Geometry
+	var err error
+	switch geo.Type {
+	case "Point":
+		var p model.Point
+		err = json.Unmarshal(raw, &p)
+		res = p
+	case "LineString":
+		var l model.LineString
+		err = json.Unmarshal(raw, &l)
+		res = l
+	case "Polygon":
+		var p model.Polygon
+		err = json.Unmarshal(raw, &p)
+		res = p
+	default:
+		return nil, fmt.Errorf("unknown geometry type %q", geo.Type)
+	}
+	return res, err
+}
+
+// sortKeys converts maps to documents with sorted keys, so encoding is stable
+func sortKeys(val interface{}) interface{} {
+	switch v := val.(type) {
+	case bson.M:
+		doc := make(bson.D, 0, len(v))
+		for _, key := range sortedKeys(v) {
+			doc = append(doc, bson.E{Key: key, Value: sortKeys(v[key])})
+		}
+		return doc
+	case map[string]interface{}:
+		return sortKeys(bson.M(v))
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = sortKeys(item)
+		}
+		return res
+	case bson.A:
+		return sortKeys([]interface{}(v))
+	}
+	return val
+}
+
+func (c Eq) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Eq) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Ne) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Ne) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Lt) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Lt) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Le) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Le) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Gt) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Gt) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Ge) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Ge) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c In) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *In) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c NotIn) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *NotIn) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Match) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Match) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Regex) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Regex) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Contains) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Contains) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c IsNull) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *IsNull) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c NotNull) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *NotNull) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Exists) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Exists) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Type) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Type) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c All) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *All) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Size) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Size) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c ElemMatch) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *ElemMatch) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Mod) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Mod) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Expr) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Expr) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Text) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Text) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Or) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Or) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c And) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *And) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Not) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Not) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c Near) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *Near) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c NearSphere) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *NearSphere) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c GeoWithin) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *GeoWithin) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
+
+func (c GeoIntersects) MarshalJSON() ([]byte, error) {
+	return marshalCondition(c)
+}
+
+func (c *GeoIntersects) UnmarshalJSON(data []byte) error {
+	return unmarshalCondition(data, c)
+}
@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"encoding/json"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestFilter_JSON(t *testing.T) {
+	id := primitive.NewObjectID()
+	f := Filter{
+		Eq{"_id": id},
+		Ne{"name": nil},
+		Lt{"createdAt": time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Le{"value": int64(5)},
+		Gt{"value": 1.5},
+		Ge{"value": 1},
+		In{"tags": {"a", "b"}},
+		NotIn{"tags": {"c"}},
+		All{"tags": {"a"}},
+		Match{"name": "^a"},
+		Regex{"name": primitive.Regex{Pattern: "^b", Options: "i"}},
+		Contains{"name": "c"},
+		IsNull("deletedAt"),
+		NotNull("name"),
+		Exists{"tags": true},
+		Type{"value": {"int", "long"}},
+		Size{"tags": 2},
+		Mod{"value": {2, 1}},
+		ElemMatch{"items": Filter{Eq{"sku": "a"}, Gt{"qty": 5}}},
+		Expr{"$gt": bson.A{"$spent", bson.M{"$multiply": bson.A{"$budget", 2}}}},
+		Text{Search: "coffee", Language: "en", CaseSensitive: true},
+		Or{Eq{"a": 1}, And{Eq{"b": 2}, Not{Eq{"c": 3}}}},
+		Near{"location": Proximity{Point: model.NewPoint(1, 2), MaxDistance: 100}},
+		NearSphere{"location": Proximity{Point: model.NewPoint(1, 2), MinDistance: 10}},
+		GeoWithin{"location": Box{BottomLeft: [2]float64{0, 0}, TopRight: [2]float64{1, 1}}},
+		GeoWithin{"location": CenterSphere{Center: [2]float64{0, 0}, Radius: 0.1}},
+		GeoWithin{"location": model.NewPolygon([][]float64{{0, 0}, {0, 1}, {1, 1}, {0, 0}})},
+		GeoIntersects{"area": model.NewLineString([]float64{0, 0}, []float64{1, 1})},
+	}
+
+	data, err := json.Marshal(f)
+	require.NoError(t, err)
+
+	var restored Filter
+	require.NoError(t, json.Unmarshal(data, &restored))
+	require.Len(t, restored, len(f))
+
+	expected, err := marshalValue(f.Apply())
+	require.NoError(t, err)
+	actual, err := marshalValue(restored.Apply())
+	require.NoError(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+
+	again, err := json.Marshal(restored)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(again))
+}
+
+func TestCondition_JSON(t *testing.T) {
+	data, err := json.Marshal(Or{Gt{"value": 5}, IsNull("name")})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"op": "or", "conditions": [
+		{"op": "gt", "field": "value", "value": {"$numberInt": "5"}},
+		{"op": "isNull", "field": "name"}
+	]}`, string(data))
+
+	var or Or
+	require.NoError(t, json.Unmarshal(data, &or))
+	require.Equal(t, Or{Gt{"value": int32(5)}, IsNull("name")}, or)
+
+	c, err := UnmarshalCondition(data)
+	require.NoError(t, err)
+	require.Equal(t, or, c)
+
+	var and And
+	require.EqualError(t, json.Unmarshal(data, &and), "can't unmarshal filter.Or condition into filter.And")
+	require.EqualError(t, json.Unmarshal([]byte(`[{"op": "like"}]`), &Filter{}), `unknown condition "like"`)
+
+	_, err = json.Marshal(Eq{"a": 1, "b": 2})
+	require.Error(t, err)
+}
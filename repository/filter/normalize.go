@@ -0,0 +1,184 @@
+package filter
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"strings"
+)
+
+// Normalize returns canonical form of query: nested $and and $or are flattened,
+// single element $and and $or are unwrapped and operators on the same field are
+// merged into one document, conflicting conditions and repeated top level operators
+// like $expr are kept in $and, empty $or and $nor are dropped
+func Normalize(query bson.M) bson.M {
+	n := normalizer{result: bson.M{}}
+	n.add(query)
+	if len(n.rest) > 0 {
+		n.result["$and"] = n.rest
+	}
+	return n.result
+}
+
+type normalizer struct {
+	result bson.M
+	rest   []interface{}
+}
+
+func (n *normalizer) add(query bson.M) {
+	for _, key := range queryKeys(query) {
+		val := query[key]
+		switch key {
+		case "$and":
+			list, ok := toList(val)
+			if !ok {
+				n.merge(key, val)
+				continue
+			}
+			for _, item := range list {
+				n.addItem(item)
+			}
+		case "$or":
+			list, ok := toList(val)
+			if !ok {
+				n.merge(key, val)
+				continue
+			}
+			var or []interface{}
+			for _, item := range list {
+				if m, ok := item.(bson.M); ok {
+					m = Normalize(m)
+					if nested, ok := m["$or"]; ok && len(m) == 1 {
+						nestedList, _ := toList(nested)
+						or = append(or, nestedList...)
+						continue
+					}
+					item = m
+				}
+				or = append(or, item)
+			}
+			switch len(or) {
+			case 0:
+				continue
+			case 1:
+				n.addItem(or[0])
+				continue
+			}
+			n.merge(key, or)
+		case "$nor":
+			list, ok := toList(val)
+			if !ok {
+				n.merge(key, val)
+				continue
+			}
+			if len(list) == 0 {
+				continue
+			}
+			nor := make([]interface{}, 0, len(list))
+			for _, item := range list {
+				if m, ok := item.(bson.M); ok {
+					item = Normalize(m)
+				}
+				nor = append(nor, item)
+			}
+			n.merge(key, nor)
+		default:
+			n.merge(key, val)
+		}
+	}
+}
+
+func (n *normalizer) addItem(item interface{}) {
+	m, ok := item.(bson.M)
+	if !ok {
+		n.rest = append(n.rest, item)
+		return
+	}
+	n.add(Normalize(m))
+}
+
+// merge adds field condition to result, operator documents of field are merged unless they have
+// common operators, top level operators like $expr are never merged
+func (n *normalizer) merge(key string, val interface{}) {
+	prev, found := n.result[key]
+	if !found {
+		n.result[key] = val
+		return
+	}
+	if strings.HasPrefix(key, "$") {
+		n.rest = append(n.rest, bson.M{key: val})
+		return
+	}
+
+	prevOps, ok := operators(prev)
+	ops, ok2 := operators(val)
+	if ok && ok2 {
+		merged := make(bson.M, len(prevOps)+len(ops))
+		for op, arg := range prevOps {
+			merged[op] = arg
+		}
+		conflict := false
+		for op, arg := range ops {
+			if _, exists := merged[op]; exists {
+				conflict = true
+				break
+			}
+			merged[op] = arg
+		}
+		if !conflict {
+			n.result[key] = merged
+			return
+		}
+	}
+	n.rest = append(n.rest, bson.M{key: val})
+}
+
+// operators returns value as operator document, e.g. {"$gt": 1, "$lt": 5}
+func operators(val interface{}) (bson.M, bool) {
+	m, ok := val.(bson.M)
+	if !ok || len(m) == 0 {
+		return nil, false
+	}
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return nil, false
+		}
+	}
+	return m, true
+}
+
+func toList(val interface{}) ([]interface{}, bool) {
+	switch v := val.(type) {
+	case []interface{}:
+		return v, true
+	case bson.A:
+		return v, true
+	case []bson.M:
+		res := make([]interface{}, 0, len(v))
+		for _, m := range v {
+			res = append(res, m)
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// queryKeys returns sorted keys with $and last, so conditions already in result take precedence over
+// conflicting ones kept in $and and normalized query doesn't change on next pass
+func queryKeys(query bson.M) []string {
+	keys := sortedKeys(query)
+	for i, key := range keys {
+		if key == "$and" {
+			return append(append(keys[:i:i], keys[i+1:]...), key)
+		}
+	}
+	return keys
+}
+
+func sortedKeys(m bson.M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
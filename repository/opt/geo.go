@@ -0,0 +1,62 @@
+package opt
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/filter"
+)
+
+// earthRadius is an equatorial radius in meters used to convert distances to radians
+const earthRadius = 6378100
+
+// Near adds condition that geometry is near point, distances are in meters and zero is not applied,
+// results are sorted by distance so sorting option should not be set, requires 2dsphere index
+func Near(column string, point model.Point, maxDistance, minDistance float64) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Near{column: filter.Proximity{
+			Point:       point,
+			MaxDistance: maxDistance,
+			MinDistance: minDistance,
+		}})
+	}
+}
+
+// NearSphere adds condition like Near that calculates distances on sphere
+func NearSphere(column string, point model.Point, maxDistance, minDistance float64) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.NearSphere{column: filter.Proximity{
+			Point:       point,
+			MaxDistance: maxDistance,
+			MinDistance: minDistance,
+		}})
+	}
+}
+
+// GeoWithin adds condition that geometry is within shape: model.Polygon, Box or CenterSphere
+func GeoWithin(column string, shape interface{}) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.GeoWithin{column: shape})
+	}
+}
+
+// GeoIntersects adds condition that geometry intersects with geometry, e.g. model.LineString
+func GeoIntersects(column string, geometry model.Geometry) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.GeoIntersects{column: geometry})
+	}
+}
+
+// Box returns GeoWithin rectangle shape by bottom left and top right corners, it works with 2dsphere index
+func Box(bottomLeft, topRight model.Point) filter.Box {
+	return filter.Box{
+		BottomLeft: [2]float64{bottomLeft.Lng(), bottomLeft.Lat()},
+		TopRight:   [2]float64{topRight.Lng(), topRight.Lat()},
+	}
+}
+
+// CenterSphere returns GeoWithin circle shape by center and radius in meters
+func CenterSphere(center model.Point, radius float64) filter.CenterSphere {
+	return filter.CenterSphere{
+		Center: [2]float64{center.Lng(), center.Lat()},
+		Radius: radius / earthRadius,
+	}
+}
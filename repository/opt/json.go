@@ -0,0 +1,74 @@
+package opt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// optJSON is Opt without methods to avoid recursion of JSON marshaling
+type optJSON Opt
+
+// MarshalJSON encodes index hint as name or as key document in canonical extended JSON
+func (o Opt) MarshalJSON() ([]byte, error) {
+	hint, err := marshalHint(o.Hint)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		optJSON
+		Hint json.RawMessage `json:"hint,omitempty"`
+	}{optJSON: optJSON(o), Hint: hint})
+}
+
+// UnmarshalJSON decodes index hint as string or bson.D
+func (o *Opt) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		*optJSON
+		Hint json.RawMessage `json:"hint,omitempty"`
+	}{optJSON: (*optJSON)(o)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	hint, err := unmarshalHint(aux.Hint)
+	if err != nil {
+		return err
+	}
+	o.Hint = hint
+	return nil
+}
+
+func marshalHint(hint interface{}) (json.RawMessage, error) {
+	switch v := hint.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return json.Marshal(v)
+	}
+
+	data, err := bson.MarshalExtJSON(hint, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("hint: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalHint(data json.RawMessage) (interface{}, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if data[0] == '"' {
+		var name string
+		err := json.Unmarshal(data, &name)
+		return name, err
+	}
+
+	var keys bson.D
+	if err := bson.UnmarshalExtJSON(data, true, &keys); err != nil {
+		return nil, fmt.Errorf("hint: %w", err)
+	}
+	return keys, nil
+}
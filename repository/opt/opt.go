@@ -3,17 +3,29 @@ package opt
 import (
 	"github.com/sanches1984/gopkg-mongo-orm/repository/filter"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"reflect"
+	"regexp"
+	"time"
 )
 
-// Opt is options for database requests
+// Opt is options for database requests, it could be stored as JSON and restored with From
 type Opt struct {
-	Skip      int64
-	Limit     int64
-	SortBy    string
-	SortOrder int
-	Filter    filter.Filter
+	Skip      int64         `json:"skip,omitempty"`
+	Limit     int64         `json:"limit,omitempty"`
+	SortBy    string        `json:"sortBy,omitempty"`
+	SortOrder int           `json:"sortOrder,omitempty"`
+	Filter    filter.Filter `json:"filter,omitempty"`
+	// TextScore is a field that gets full-text search score
+	TextScore string `json:"textScore,omitempty"`
+
+	Hint         interface{}        `json:"hint,omitempty"`
+	Collation    *options.Collation `json:"collation,omitempty"`
+	MaxTime      time.Duration      `json:"maxTime,omitempty"`
+	Comment      string             `json:"comment,omitempty"`
+	AllowDiskUse bool               `json:"allowDiskUse,omitempty"`
+	BatchSize    int32              `json:"batchSize,omitempty"`
 }
 
 // FnOpt is a function that modifies options
@@ -47,13 +59,52 @@ func (o *Opt) GetOptions() *options.FindOptions {
 		opts.SetSkip(o.Skip).SetLimit(o.Limit)
 	}
 
-	if o.IsSorting() {
-		opts.SetSort(bson.D{{o.SortBy, o.SortOrder}})
+	if projection := o.projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if sort := o.sort(); sort != nil {
+		opts.SetSort(sort)
+	}
+
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.MaxTime > 0 {
+		opts.SetMaxTime(o.MaxTime)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	if o.AllowDiskUse {
+		opts.SetAllowDiskUse(true)
+	}
+	if o.BatchSize > 0 {
+		opts.SetBatchSize(o.BatchSize)
 	}
 
 	return opts
 }
 
+func (o *Opt) projection() bson.M {
+	if o.TextScore == "" {
+		return nil
+	}
+	return bson.M{o.TextScore: bson.M{"$meta": "textScore"}}
+}
+
+func (o *Opt) sort() bson.D {
+	if o.IsSorting() {
+		return bson.D{{Key: o.SortBy, Value: o.SortOrder}}
+	}
+	if o.TextScore != "" {
+		return bson.D{{Key: o.TextScore, Value: bson.M{"$meta": "textScore"}}}
+	}
+	return nil
+}
+
 // GetFilter returns filter
 func GetFilter(optFn ...FnOpt) bson.M {
 	return New(optFn...).GetFilter()
@@ -79,6 +130,22 @@ func (o *Opt) IsFilter() bool {
 	return len(o.Filter) > 0
 }
 
+// From copies options, e.g. unmarshalled from JSON, conditions are appended to filter
+func From(o *Opt) FnOpt {
+	return func(opt *Opt) {
+		conds := append(opt.Filter, o.Filter...)
+		*opt = *o
+		opt.Filter = conds
+	}
+}
+
+// Conditions adds filter's conditions, e.g. filter unmarshalled from JSON
+func Conditions(f filter.Filter) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, f...)
+	}
+}
+
 // List converts periodic opts args into slice
 func List(optFn ...FnOpt) []FnOpt {
 	return optFn
@@ -153,27 +220,133 @@ func Neq(column string, val interface{}) FnOpt {
 // In sets condition for IN operation
 func In(column string, vals interface{}) FnOpt {
 	return func(opt *Opt) {
-		if reflect.TypeOf(vals).Kind() != reflect.Slice {
-			vals = []interface{}{vals}
-		}
+		opt.Filter = append(opt.Filter, filter.In{column: toSlice(vals)})
+	}
+}
 
-		in := []interface{}{}
-		v := reflect.ValueOf(vals)
-		for i := 0; i < v.Len(); i++ {
-			in = append(in, v.Index(i).Interface())
-		}
+// NotIn sets condition for NOT IN operation
+func NotIn(column string, vals interface{}) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.NotIn{column: toSlice(vals)})
+	}
+}
+
+// All adds condition that array contains all values
+func All(column string, vals interface{}) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.All{column: toSlice(vals)})
+	}
+}
+
+// Size adds condition on array length
+func Size(column string, size int) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Size{column: size})
+	}
+}
+
+// ElemMatch adds condition that array has element matching all conditions,
+// use empty column in conditions to compare element itself, e.g. opt.Gt("", 5)
+func ElemMatch(column string, optFn ...FnOpt) FnOpt {
+	return func(opt *Opt) {
+		o := New(optFn...)
+		opt.Filter = append(opt.Filter, filter.ElemMatch{column: o.Filter})
+	}
+}
+
+// Exists adds condition on field existence
+func Exists(column string, exists bool) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Exists{column: exists})
+	}
+}
+
+// Type adds condition that field has one of BSON types, e.g. "string", "int", "date"
+func Type(column string, types ...string) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Type{column: types})
+	}
+}
 
-		opt.Filter = append(opt.Filter, filter.In{column: in})
+// Mod adds condition that field divided by divisor has remainder
+func Mod(column string, divisor, remainder int64) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Mod{column: {divisor, remainder}})
+	}
+}
+
+// Regex adds regular expression condition with options, e.g. "i" for case insensitive
+func Regex(column, pattern, flags string) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Regex{column: primitive.Regex{Pattern: pattern, Options: flags}})
 	}
 }
 
-// Contains builds a condition with contains statement
+// Expr adds aggregation expression condition, e.g. bson.M{"$gt": bson.A{"$spent", "$budget"}}
+func Expr(expr bson.M) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Expr(expr))
+	}
+}
+
+// toSlice converts slice of any type or single value into []interface{}
+func toSlice(vals interface{}) []interface{} {
+	if reflect.TypeOf(vals).Kind() != reflect.Slice {
+		vals = []interface{}{vals}
+	}
+
+	res := []interface{}{}
+	v := reflect.ValueOf(vals)
+	for i := 0; i < v.Len(); i++ {
+		res = append(res, v.Index(i).Interface())
+	}
+	return res
+}
+
+// Contains builds a case-insensitive condition with contains statement, value is a regular expression,
+// use ContainsLiteral for user input
 func Contains(column string, val string) FnOpt {
 	return func(opt *Opt) {
 		opt.Filter = append(opt.Filter, filter.Contains{column: val})
 	}
 }
 
+// ContainsLiteral builds a case-insensitive contains condition, regex metacharacters in value are escaped
+func ContainsLiteral(column string, val string) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Contains{column: regexp.QuoteMeta(val)})
+	}
+}
+
+// Prefix builds a condition that value starts with prefix, it's case-sensitive so index could be used
+func Prefix(column string, prefix string) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Match{column: "^" + regexp.QuoteMeta(prefix)})
+	}
+}
+
+// DefaultTextScore is a field that gets full-text search score unless TextScore is set
+const DefaultTextScore = "score"
+
+// Text adds full-text search condition, language is optional, results are sorted by relevance
+// unless sorting is set and score is set to DefaultTextScore field or to TextScore one, requires text index
+func Text(query, language string, caseSensitive bool) FnOpt {
+	return func(opt *Opt) {
+		opt.Filter = append(opt.Filter, filter.Text{Search: query, Language: language, CaseSensitive: caseSensitive})
+		if opt.TextScore == "" {
+			opt.TextScore = DefaultTextScore
+		}
+	}
+}
+
+// TextScore sets model's field that gets full-text search score, empty field after Text
+// disables score projection and sorting by relevance
+func TextScore(field string) FnOpt {
+	return func(opt *Opt) {
+		opt.TextScore = field
+	}
+}
+
 // Or adds set of conditions joined with OR statement
 func Or(optFn ...FnOpt) FnOpt {
 	return func(opt *Opt) {
@@ -204,7 +377,7 @@ func IsNull(column string) FnOpt {
 	}
 }
 
-// Not adds `NOT` condition
+// Not adds `NOT` condition negating all given conditions joined with AND
 func Not(optFn ...FnOpt) FnOpt {
 	return func(opt *Opt) {
 		o := New(optFn...)
@@ -0,0 +1,257 @@
+package opt
+
+import (
+	"encoding/json"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"testing"
+	"time"
+)
+
+func TestConditions(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  FnOpt
+		cond bson.M
+	}{
+		{
+			name: "exists",
+			opt:  Exists("name", true),
+			cond: bson.M{"name": bson.M{"$exists": true}},
+		},
+		{
+			name: "type",
+			opt:  Type("value", "int", "long"),
+			cond: bson.M{"value": bson.M{"$type": []string{"int", "long"}}},
+		},
+		{
+			name: "single type",
+			opt:  Type("value", "string"),
+			cond: bson.M{"value": bson.M{"$type": "string"}},
+		},
+		{
+			name: "all",
+			opt:  All("tags", []string{"a", "b"}),
+			cond: bson.M{"tags": bson.M{"$all": []interface{}{"a", "b"}}},
+		},
+		{
+			name: "size",
+			opt:  Size("tags", 2),
+			cond: bson.M{"tags": bson.M{"$size": 2}},
+		},
+		{
+			name: "not in",
+			opt:  NotIn("value", 5),
+			cond: bson.M{"value": bson.M{"$nin": []interface{}{5}}},
+		},
+		{
+			name: "mod",
+			opt:  Mod("value", 4, 1),
+			cond: bson.M{"value": bson.M{"$mod": []int64{4, 1}}},
+		},
+		{
+			name: "regex",
+			opt:  Regex("name", "^hello", "im"),
+			cond: bson.M{"name": bson.M{"$regex": "^hello", "$options": "im"}},
+		},
+		{
+			name: "regex without flags",
+			opt:  Regex("name", "^hello", ""),
+			cond: bson.M{"name": bson.M{"$regex": "^hello"}},
+		},
+		{
+			name: "expr",
+			opt:  Expr(bson.M{"$gt": bson.A{"$spent", "$budget"}}),
+			cond: bson.M{"$expr": bson.M{"$gt": bson.A{"$spent", "$budget"}}},
+		},
+		{
+			name: "elem match",
+			opt:  ElemMatch("items", Eq("sku", "a"), Gt("qty", 5)),
+			cond: bson.M{"items": bson.M{"$elemMatch": bson.M{
+				"sku": bson.M{"$eq": "a"},
+				"qty": bson.M{"$gt": 5},
+			}}},
+		},
+		{
+			name: "elem match of values",
+			opt:  ElemMatch("scores", Ge("", 80), Lt("", 85)),
+			cond: bson.M{"scores": bson.M{"$elemMatch": bson.M{"$gte": 80, "$lt": 85}}},
+		},
+		{
+			name: "elem match of repeated operators",
+			opt:  ElemMatch("scores", Gt("", 5), Gt("", 10), Lt("", 30), Lt("", 20), Ge("", 1)),
+			cond: bson.M{"scores": bson.M{"$elemMatch": bson.M{"$gt": 10, "$lt": 20, "$gte": 1}}},
+		},
+		{
+			name: "elem match of repeated sets",
+			opt:  ElemMatch("tags", In("", []string{"a", "b", "c"}), In("", []string{"b", "c", "d"}), Neq("", "b"), Neq("", "x")),
+			cond: bson.M{"tags": bson.M{"$elemMatch": bson.M{
+				"$in":  []interface{}{"b", "c"},
+				"$nin": []interface{}{"b", "x"},
+			}}},
+		},
+		{
+			name: "elem match single",
+			opt:  ElemMatch("items", Eq("sku", "a")),
+			cond: bson.M{"items": bson.M{"$elemMatch": bson.M{"sku": bson.M{"$eq": "a"}}}},
+		},
+		{
+			name: "text",
+			opt:  Text("coffee shop", "en", true),
+			cond: bson.M{"$text": bson.M{"$search": "coffee shop", "$language": "en", "$caseSensitive": true}},
+		},
+		{
+			name: "contains literal",
+			opt:  ContainsLiteral("name", "a.b*"),
+			cond: bson.M{"name": bson.M{"$regex": `a\.b\*`, "$options": "i"}},
+		},
+		{
+			name: "prefix",
+			opt:  Prefix("name", "(c)"),
+			cond: bson.M{"name": bson.M{"$regex": `^\(c\)`}},
+		},
+		{
+			name: "near",
+			opt:  Near("location", model.NewPoint(37.6, 55.7), 1000, 0),
+			cond: bson.M{"location": bson.M{"$near": bson.M{
+				"$geometry":    model.Point{Type: "Point", Coordinates: []float64{37.6, 55.7}},
+				"$maxDistance": 1000.0,
+			}}},
+		},
+		{
+			name: "near sphere",
+			opt:  NearSphere("location", model.NewPoint(37.6, 55.7), 0, 10),
+			cond: bson.M{"location": bson.M{"$nearSphere": bson.M{
+				"$geometry":    model.NewPoint(37.6, 55.7),
+				"$minDistance": 10.0,
+			}}},
+		},
+		{
+			name: "within polygon",
+			opt:  GeoWithin("location", model.NewPolygon([][]float64{{0, 0}, {0, 1}, {1, 1}, {0, 0}})),
+			cond: bson.M{"location": bson.M{"$geoWithin": bson.M{
+				"$geometry": model.NewPolygon([][]float64{{0, 0}, {0, 1}, {1, 1}, {0, 0}}),
+			}}},
+		},
+		{
+			name: "within box",
+			opt:  GeoWithin("location", Box(model.NewPoint(0, 0), model.NewPoint(1, 2))),
+			cond: bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": model.NewPolygon(
+				[][]float64{{0, 0}, {1, 0}, {1, 2}, {0, 2}, {0, 0}},
+			)}}},
+		},
+		{
+			name: "within center sphere",
+			opt:  GeoWithin("location", CenterSphere(model.NewPoint(1, 2), earthRadius)),
+			cond: bson.M{"location": bson.M{"$geoWithin": bson.M{"$centerSphere": []interface{}{[2]float64{1, 2}, 1.0}}}},
+		},
+		{
+			name: "intersects",
+			opt:  GeoIntersects("area", model.NewLineString([]float64{0, 0}, []float64{1, 1})),
+			cond: bson.M{"area": bson.M{"$geoIntersects": bson.M{
+				"$geometry": model.NewLineString([]float64{0, 0}, []float64{1, 1}),
+			}}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			o := New(c.opt)
+			require.Len(t, o.Filter, 1)
+			require.Equal(t, c.cond, o.Filter[0].Condition())
+		})
+	}
+}
+
+func TestTextScore(t *testing.T) {
+	score := bson.M{"$meta": "textScore"}
+
+	opts := GetOptions(Text("coffee", "", false))
+	require.Equal(t, bson.M{"score": score}, opts.Projection)
+	require.Equal(t, bson.D{{Key: "score", Value: score}}, opts.Sort)
+
+	opts = GetOptions(Text("coffee", "", false), Asc("name"))
+	require.Equal(t, bson.M{"score": score}, opts.Projection)
+	require.Equal(t, bson.D{{Key: "name", Value: 1}}, opts.Sort)
+	require.Equal(t, bson.M{"$text": bson.M{"$search": "coffee"}}, GetFilter(Text("coffee", "", false)))
+
+	opts = GetOptions(TextScore("relevance"), Text("coffee", "", false))
+	require.Equal(t, bson.M{"relevance": score}, opts.Projection)
+	require.Equal(t, bson.D{{Key: "relevance", Value: score}}, opts.Sort)
+
+	opts = GetOptions(Text("coffee", "", false), TextScore(""))
+	require.Nil(t, opts.Projection)
+	require.Nil(t, opts.Sort)
+}
+
+func TestQueryOptions(t *testing.T) {
+	collation := &options.Collation{Locale: "en", Strength: 2}
+	o := New(Hint("name_1"), Collation("en", 2), MaxTime(time.Second), Comment("books"),
+		AllowDiskUse(), BatchSize(50), Asc("name"), Paging(2, 10))
+
+	find := o.GetOptions()
+	require.Equal(t, "name_1", find.Hint)
+	require.Equal(t, collation, find.Collation)
+	require.Equal(t, time.Second, *find.MaxTime)
+	require.Equal(t, "books", *find.Comment)
+	require.True(t, *find.AllowDiskUse)
+	require.Equal(t, int32(50), *find.BatchSize)
+
+	one := o.GetFindOneOptions()
+	require.Equal(t, "name_1", one.Hint)
+	require.Equal(t, int64(10), *one.Skip)
+	require.Equal(t, bson.D{{Key: "name", Value: 1}}, one.Sort)
+
+	count := o.GetCountOptions()
+	require.Equal(t, collation, count.Collation)
+	require.Nil(t, count.Skip)
+	require.Nil(t, count.Limit)
+
+	update := o.GetUpdateOptions()
+	require.Equal(t, "name_1", update.Hint)
+	require.Equal(t, "books", update.Comment)
+
+	del := o.GetDeleteOptions()
+	require.Equal(t, collation, del.Collation)
+
+	aggregate := o.GetAggregateOptions()
+	require.True(t, *aggregate.AllowDiskUse)
+	require.Equal(t, time.Second, *aggregate.MaxTime)
+
+	empty := New().GetOptions()
+	require.Nil(t, empty.Hint)
+	require.Nil(t, empty.Collation)
+	require.Nil(t, empty.MaxTime)
+}
+
+func TestOpt_JSON(t *testing.T) {
+	o := New(Eq("name", "hello"), Or(Gt("value", 5), IsNull("value")), Desc("createdAt"), Paging(2, 10),
+		Hint(bson.D{{Key: "name", Value: int32(1)}, {Key: "value", Value: int32(-1)}}))
+	data, err := json.Marshal(o)
+	require.NoError(t, err)
+
+	var restored Opt
+	require.NoError(t, json.Unmarshal(data, &restored))
+
+	r := New(Exists("name", true), From(&restored))
+	require.Equal(t, o.GetOptions(), r.GetOptions())
+	require.Equal(t, bson.M{
+		"name": bson.M{"$exists": true, "$eq": "hello"},
+		"$or": []interface{}{
+			bson.M{"value": bson.M{"$gt": int32(5)}},
+			bson.M{"value": bson.M{"$eq": nil}},
+		},
+	}, r.GetFilter())
+
+	require.Equal(t, o.GetFilter(), GetFilter(Conditions(o.Filter)))
+	require.Equal(t, bson.D{{Key: "name", Value: int32(1)}, {Key: "value", Value: int32(-1)}}, restored.Hint)
+
+	data, err = json.Marshal(New(Hint("name_1")))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"hint": "name_1"}`, string(data))
+	restored = Opt{}
+	require.NoError(t, json.Unmarshal(data, &restored))
+	require.Equal(t, "name_1", restored.Hint)
+}
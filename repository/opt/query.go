@@ -0,0 +1,152 @@
+package opt
+
+import (
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// Hint forces query to use index by name or keys, e.g. "name_1" or bson.D{{Key: "name", Value: 1}}
+func Hint(index interface{}) FnOpt {
+	return func(opt *Opt) {
+		opt.Hint = index
+	}
+}
+
+// Collation sets language rules of string comparison and sorting,
+// strength 1 or 2 compares case-insensitive, index must have the same collation to be used
+func Collation(locale string, strength int) FnOpt {
+	return func(opt *Opt) {
+		opt.Collation = &options.Collation{Locale: locale, Strength: strength}
+	}
+}
+
+// MaxTime sets server time limit of query, it's a context timeout for UpdateWhere and DeleteWhere
+func MaxTime(d time.Duration) FnOpt {
+	return func(opt *Opt) {
+		opt.MaxTime = d
+	}
+}
+
+// Comment sets comment shown in profiler and logs of server
+func Comment(s string) FnOpt {
+	return func(opt *Opt) {
+		opt.Comment = s
+	}
+}
+
+// AllowDiskUse allows server to use temporary files for large sorting and aggregation
+func AllowDiskUse() FnOpt {
+	return func(opt *Opt) {
+		opt.AllowDiskUse = true
+	}
+}
+
+// BatchSize sets number of documents returned in each batch of cursor
+func BatchSize(n int32) FnOpt {
+	return func(opt *Opt) {
+		opt.BatchSize = n
+	}
+}
+
+// GetFindOneOptions returns find one options, limit is ignored
+func (o *Opt) GetFindOneOptions() *options.FindOneOptions {
+	opts := options.FindOne()
+	if o.Skip > 0 {
+		opts.SetSkip(o.Skip)
+	}
+	if projection := o.projection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if sort := o.sort(); sort != nil {
+		opts.SetSort(sort)
+	}
+
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.MaxTime > 0 {
+		opts.SetMaxTime(o.MaxTime)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	if o.BatchSize > 0 {
+		opts.SetBatchSize(o.BatchSize)
+	}
+	return opts
+}
+
+// GetCountOptions returns count options, paging is ignored to count all matching documents
+func (o *Opt) GetCountOptions() *options.CountOptions {
+	opts := options.Count()
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.MaxTime > 0 {
+		opts.SetMaxTime(o.MaxTime)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	return opts
+}
+
+// GetUpdateOptions returns update options
+func (o *Opt) GetUpdateOptions() *options.UpdateOptions {
+	opts := options.Update()
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	return opts
+}
+
+// GetDeleteOptions returns delete options
+func (o *Opt) GetDeleteOptions() *options.DeleteOptions {
+	opts := options.Delete()
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	return opts
+}
+
+// GetAggregateOptions returns aggregate options, filter, sorting and paging are not applied
+func (o *Opt) GetAggregateOptions() *options.AggregateOptions {
+	opts := options.Aggregate()
+	if o.Hint != nil {
+		opts.SetHint(o.Hint)
+	}
+	if o.Collation != nil {
+		opts.SetCollation(o.Collation)
+	}
+	if o.MaxTime > 0 {
+		opts.SetMaxTime(o.MaxTime)
+	}
+	if o.Comment != "" {
+		opts.SetComment(o.Comment)
+	}
+	if o.AllowDiskUse {
+		opts.SetAllowDiskUse(true)
+	}
+	if o.BatchSize > 0 {
+		opts.SetBatchSize(o.BatchSize)
+	}
+	return opts
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// coerce converts parameter value to type of model's field, array fields use element type
+func coerce(key string, t reflect.Type, val string) (interface{}, error) {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if v, err := time.Parse(layout, val); err == nil {
+				return v, nil
+			}
+		}
+		return nil, &Error{Param: key, Reason: "must be a date in RFC 3339 format"}
+	case objectIDType:
+		v, err := primitive.ObjectIDFromHex(val)
+		if err != nil {
+			return nil, &Error{Param: key, Reason: "must be an ObjectID"}
+		}
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return val, nil
+	case reflect.Bool:
+		v, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, &Error{Param: key, Reason: "must be true or false"}
+		}
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return nil, &Error{Param: key, Reason: "must be an integer"}
+		}
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return nil, &Error{Param: key, Reason: "must be a number"}
+		}
+		return v, nil
+	}
+	return nil, &Error{Param: key, Reason: fmt.Sprintf("filtering by %s is not supported", t)}
+}
@@ -0,0 +1,38 @@
+package query
+
+// OptionFn is a function that configures parser
+type OptionFn func(p *Parser)
+
+// Field allows filtering by model's field with operators, equality and in are allowed by default
+func Field(name string, ops ...Operator) OptionFn {
+	return func(p *Parser) {
+		if len(ops) == 0 {
+			ops = []Operator{Eq, In}
+		}
+		p.filters[name] = ops
+	}
+}
+
+// Sort allows sorting by model's fields
+func Sort(names ...string) OptionFn {
+	return func(p *Parser) {
+		for _, name := range names {
+			p.sorts[name] = struct{}{}
+		}
+	}
+}
+
+// PageSize sets default and max page size, default is 20 and max is 100
+func PageSize(size, max int) OptionFn {
+	return func(p *Parser) {
+		p.pageSize = size
+		p.maxPageSize = max
+	}
+}
+
+// IgnoreUnknown skips parameters that are not allowed instead of returning error
+func IgnoreUnknown() OptionFn {
+	return func(p *Parser) {
+		p.ignoreUnknown = true
+	}
+}
@@ -0,0 +1,275 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operator is a filter operator in query parameter, e.g. age[gte]=18
+type Operator string
+
+const (
+	Eq       Operator = "eq"
+	Ne       Operator = "ne"
+	Gt       Operator = "gt"
+	Gte      Operator = "gte"
+	Lt       Operator = "lt"
+	Lte      Operator = "lte"
+	In       Operator = "in"
+	Nin      Operator = "nin"
+	Exists   Operator = "exists"
+	Prefix   Operator = "prefix"
+	Contains Operator = "contains"
+)
+
+const (
+	paramSort = "sort"
+	paramPage = "page"
+	paramSize = "size"
+)
+
+// ErrBadRequest is matched by errors.Is with all parsing errors
+var ErrBadRequest = errors.New("bad request")
+
+// Error is an incorrect query parameter
+type Error struct {
+	Param  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query parameter %q: %s", e.Param, e.Reason)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrBadRequest
+}
+
+var paramRe = regexp.MustCompile(`^([\w.]+)(?:\[(\w+)\])?$`)
+
+// Parser parses url query into options for model, e.g.
+// `?name=foo&age[gte]=18&tags[in]=a,b&sort=-createdAt&page=2&size=10`,
+// list values are comma separated
+type Parser struct {
+	fields        map[string]model.Field
+	filters       map[string][]Operator
+	sorts         map[string]struct{}
+	pageSize      int
+	maxPageSize   int
+	ignoreUnknown bool
+}
+
+// New creates parser for model, only fields allowed by Field and Sort options could be used
+func New(rec interface{}, optFn ...OptionFn) (*Parser, error) {
+	p := &Parser{
+		fields:      map[string]model.Field{},
+		filters:     map[string][]Operator{},
+		sorts:       map[string]struct{}{},
+		pageSize:    20,
+		maxPageSize: 100,
+	}
+	for _, f := range model.Fields(rec) {
+		p.fields[f.Name] = f
+	}
+	for _, fn := range optFn {
+		fn(p)
+	}
+
+	for name := range p.filters {
+		if _, ok := p.fields[name]; !ok {
+			return nil, fmt.Errorf("%T has no field %s", rec, name)
+		}
+	}
+	for name := range p.sorts {
+		if _, ok := p.fields[name]; !ok {
+			return nil, fmt.Errorf("%T has no field %s", rec, name)
+		}
+	}
+	return p, nil
+}
+
+// Parse converts query parameters into options, returned error matches ErrBadRequest
+func (p *Parser) Parse(values url.Values) ([]opt.FnOpt, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var res []opt.FnOpt
+	page, size := 0, 0
+	for _, key := range keys {
+		vals := values[key]
+		var err error
+		switch key {
+		case paramSort:
+			var fn opt.FnOpt
+			fn, err = p.parseSort(vals)
+			res = append(res, fn)
+		case paramPage:
+			page, err = parsePositive(key, vals)
+		case paramSize:
+			size, err = parsePositive(key, vals)
+			if err == nil && size > p.maxPageSize {
+				err = &Error{Param: key, Reason: fmt.Sprintf("must be at most %d", p.maxPageSize)}
+			}
+		default:
+			var fns []opt.FnOpt
+			fns, err = p.parseFilter(key, vals)
+			res = append(res, fns...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if page > 0 || size > 0 {
+		if page == 0 {
+			page = 1
+		}
+		if size == 0 {
+			size = p.pageSize
+		}
+		res = append(res, opt.Paging(page, size))
+	}
+	return res, nil
+}
+
+func (p *Parser) parseSort(vals []string) (opt.FnOpt, error) {
+	if len(vals) != 1 || strings.Contains(vals[0], ",") {
+		return nil, &Error{Param: paramSort, Reason: "only one sorting field is supported"}
+	}
+
+	name, desc := vals[0], false
+	if strings.HasPrefix(name, "-") {
+		name, desc = name[1:], true
+	}
+	if _, ok := p.sorts[name]; !ok {
+		return nil, &Error{Param: paramSort, Reason: fmt.Sprintf("sorting by %s is not allowed", name)}
+	}
+
+	if desc {
+		return opt.Desc(name), nil
+	}
+	return opt.Asc(name), nil
+}
+
+func (p *Parser) parseFilter(key string, vals []string) ([]opt.FnOpt, error) {
+	m := paramRe.FindStringSubmatch(key)
+	if m == nil {
+		if p.ignoreUnknown {
+			return nil, nil
+		}
+		return nil, &Error{Param: key, Reason: "unknown parameter"}
+	}
+
+	name, op := m[1], Operator(m[2])
+	allowed, ok := p.filters[name]
+	if !ok {
+		if p.ignoreUnknown {
+			return nil, nil
+		}
+		return nil, &Error{Param: key, Reason: "filtering by field is not allowed"}
+	}
+
+	if op == "" {
+		op = Eq
+		// repeated parameter is a list of values, e.g. name=a&name=b
+		if len(vals) > 1 {
+			op = In
+		}
+	}
+	if !hasOperator(allowed, op) {
+		return nil, &Error{Param: key, Reason: fmt.Sprintf("operator %s is not allowed", op)}
+	}
+
+	field := p.fields[name]
+	var res []opt.FnOpt
+	switch op {
+	case In, Nin:
+		var list []interface{}
+		for _, val := range vals {
+			for _, item := range strings.Split(val, ",") {
+				v, err := coerce(key, field.Type, item)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+		}
+		if op == In {
+			return append(res, opt.In(name, list)), nil
+		}
+		return append(res, opt.NotIn(name, list)), nil
+	}
+
+	for _, val := range vals {
+		fn, err := filterOpt(key, name, op, field.Type, val)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, fn)
+	}
+	return res, nil
+}
+
+func filterOpt(key, name string, op Operator, t reflect.Type, val string) (opt.FnOpt, error) {
+	switch op {
+	case Exists:
+		exists, err := strconv.ParseBool(val)
+		if err != nil {
+			return nil, &Error{Param: key, Reason: "must be true or false"}
+		}
+		return opt.Exists(name, exists), nil
+	case Prefix:
+		return opt.Prefix(name, val), nil
+	case Contains:
+		return opt.ContainsLiteral(name, val), nil
+	}
+
+	v, err := coerce(key, t, val)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case Eq:
+		return opt.Eq(name, v), nil
+	case Ne:
+		return opt.Neq(name, v), nil
+	case Gt:
+		return opt.Gt(name, v), nil
+	case Gte:
+		return opt.Ge(name, v), nil
+	case Lt:
+		return opt.Lt(name, v), nil
+	case Lte:
+		return opt.Le(name, v), nil
+	}
+	return nil, &Error{Param: key, Reason: fmt.Sprintf("unknown operator %s", op)}
+}
+
+func hasOperator(ops []Operator, op Operator) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePositive(key string, vals []string) (int, error) {
+	n, err := strconv.Atoi(vals[0])
+	if err != nil || n < 1 || len(vals) > 1 {
+		return 0, &Error{Param: key, Reason: "must be a positive number"}
+	}
+	return n, nil
+}
@@ -0,0 +1,166 @@
+package query
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type user struct {
+	collection         struct{} `bson:"users"`
+	model.DefaultModel `bson:",inline"`
+	Name               string             `bson:"name"`
+	Age                int                `bson:"age"`
+	Rating             float64            `bson:"rating"`
+	Active             bool               `bson:"active"`
+	Tags               []string           `bson:"tags"`
+	OwnerID            primitive.ObjectID `bson:"ownerId"`
+	Address            struct {
+		City string `bson:"city"`
+	} `bson:"address"`
+}
+
+func newParser(t *testing.T) *Parser {
+	p, err := New(&user{},
+		Field("name", Eq, In, Prefix, Contains),
+		Field("age", Gte, Lte),
+		Field("rating", Gt),
+		Field("active"),
+		Field("tags", In, Nin, Exists),
+		Field("ownerId"),
+		Field("createdAt", Gte),
+		Field("address.city"),
+		Sort("createdAt", "name"),
+		PageSize(10, 50),
+	)
+	require.NoError(t, err)
+	return p
+}
+
+func TestParser_Parse(t *testing.T) {
+	p := newParser(t)
+	ownerID := primitive.NewObjectID()
+
+	cases := []struct {
+		name   string
+		query  string
+		filter bson.M
+	}{
+		{
+			name:   "eq",
+			query:  "name=foo&active=true&address.city=Paris",
+			filter: bson.M{"name": bson.M{"$eq": "foo"}, "active": bson.M{"$eq": true}, "address.city": bson.M{"$eq": "Paris"}},
+		},
+		{
+			name:   "range",
+			query:  "age[gte]=18&age[lte]=30&rating[gt]=4.5",
+			filter: bson.M{"age": bson.M{"$gte": int64(18), "$lte": int64(30)}, "rating": bson.M{"$gt": 4.5}},
+		},
+		{
+			name:   "repeated",
+			query:  "name=a&name=b",
+			filter: bson.M{"name": bson.M{"$in": []interface{}{"a", "b"}}},
+		},
+		{
+			name:   "lists",
+			query:  "tags[in]=a,b&tags[exists]=true",
+			filter: bson.M{"tags": bson.M{"$in": []interface{}{"a", "b"}, "$exists": true}},
+		},
+		{
+			name:   "object id",
+			query:  "ownerId=" + ownerID.Hex(),
+			filter: bson.M{"ownerId": bson.M{"$eq": ownerID}},
+		},
+		{
+			name:   "date",
+			query:  "createdAt[gte]=2022-01-02",
+			filter: bson.M{"createdAt": bson.M{"$gte": time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:   "prefix",
+			query:  "name[prefix]=a.b",
+			filter: bson.M{"name": bson.M{"$regex": `^a\.b`}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			values, err := url.ParseQuery(c.query)
+			require.NoError(t, err)
+
+			opts, err := p.Parse(values)
+			require.NoError(t, err)
+			require.Equal(t, c.filter, opt.GetFilter(opts...))
+		})
+	}
+}
+
+func TestParser_SortAndPaging(t *testing.T) {
+	p := newParser(t)
+
+	opts, err := p.Parse(url.Values{"sort": {"-createdAt"}, "page": {"3"}})
+	require.NoError(t, err)
+	o := opt.New(opts...)
+	require.Equal(t, "createdAt", o.SortBy)
+	require.Equal(t, -1, o.SortOrder)
+	require.Equal(t, int64(10), o.Limit)
+	require.Equal(t, int64(20), o.Skip)
+
+	opts, err = p.Parse(url.Values{"sort": {"name"}, "size": {"5"}})
+	require.NoError(t, err)
+	o = opt.New(opts...)
+	require.Equal(t, 1, o.SortOrder)
+	require.Equal(t, int64(5), o.Limit)
+	require.Equal(t, int64(0), o.Skip)
+
+	opts, err = p.Parse(url.Values{})
+	require.NoError(t, err)
+	require.False(t, opt.New(opts...).IsPaging())
+}
+
+func TestParser_Errors(t *testing.T) {
+	p := newParser(t)
+
+	cases := map[string]string{
+		"password=x":       `invalid query parameter "password": filtering by field is not allowed`,
+		"age=18":           `invalid query parameter "age": operator eq is not allowed`,
+		"age[gte]=old":     `invalid query parameter "age[gte]": must be an integer`,
+		"ownerId=1":        `invalid query parameter "ownerId": must be an ObjectID`,
+		"createdAt[gte]=x": `invalid query parameter "createdAt[gte]": must be a date in RFC 3339 format`,
+		"active=yes":       `invalid query parameter "active": must be true or false`,
+		"sort=age":         `invalid query parameter "sort": sorting by age is not allowed`,
+		"sort=name,age":    `invalid query parameter "sort": only one sorting field is supported`,
+		"page=0":           `invalid query parameter "page": must be a positive number`,
+		"size=100":         `invalid query parameter "size": must be at most 50`,
+		"name[like]=x":     `invalid query parameter "name[like]": operator like is not allowed`,
+		"$where=x":         `invalid query parameter "$where": unknown parameter`,
+	}
+
+	for query, msg := range cases {
+		values, err := url.ParseQuery(query)
+		require.NoError(t, err)
+
+		_, err = p.Parse(values)
+		require.ErrorIs(t, err, ErrBadRequest, query)
+		require.EqualError(t, err, msg)
+	}
+
+	p, err := New(&user{}, Field("name"), IgnoreUnknown())
+	require.NoError(t, err)
+	opts, err := p.Parse(url.Values{"name": {"foo"}, "fields": {"name"}})
+	require.NoError(t, err)
+	require.Len(t, opts, 1)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&user{}, Field("password"))
+	require.EqualError(t, err, "*query.user has no field password")
+
+	_, err = New(&user{}, Sort("password"))
+	require.EqualError(t, err, "*query.user has no field password")
+}
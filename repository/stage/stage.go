@@ -0,0 +1,41 @@
+package stage
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GeoNear returns $geoNear stage that outputs documents sorted from nearest to farthest with
+// distance in meters stored in distanceField, key is a field with 2dsphere index and could be
+// empty when collection has only one, zero distances are not applied, query filters documents,
+// stage must be first in pipeline
+func GeoNear(key string, point model.Point, distanceField string, maxDistance, minDistance float64, query ...opt.FnOpt) bson.M {
+	params := bson.M{
+		"near":          point,
+		"distanceField": distanceField,
+		"spherical":     true,
+	}
+	if key != "" {
+		params["key"] = key
+	}
+	if maxDistance > 0 {
+		params["maxDistance"] = maxDistance
+	}
+	if minDistance > 0 {
+		params["minDistance"] = minDistance
+	}
+	if filter := opt.GetFilter(query...); filter != nil {
+		params["query"] = filter
+	}
+	return bson.M{"$geoNear": params}
+}
+
+// Match returns $match stage with filter built by options
+func Match(optFn ...opt.FnOpt) bson.M {
+	filter := opt.GetFilter(optFn...)
+	if filter == nil {
+		filter = bson.M{}
+	}
+	return bson.M{"$match": filter}
+}
@@ -0,0 +1,32 @@
+package stage
+
+import (
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestGeoNear(t *testing.T) {
+	point := model.NewPoint(37.6, 55.7)
+	require.Equal(t, bson.M{"$geoNear": bson.M{
+		"near":          point,
+		"distanceField": "distance",
+		"spherical":     true,
+		"key":           "location",
+		"maxDistance":   500.0,
+		"query":         bson.M{"name": bson.M{"$eq": "cafe"}},
+	}}, GeoNear("location", point, "distance", 500, 0, opt.Eq("name", "cafe")))
+
+	require.Equal(t, bson.M{"$geoNear": bson.M{
+		"near":          point,
+		"distanceField": "distance",
+		"spherical":     true,
+	}}, GeoNear("", point, "distance", 0, 0))
+}
+
+func TestMatch(t *testing.T) {
+	require.Equal(t, bson.M{"$match": bson.M{}}, Match())
+	require.Equal(t, bson.M{"$match": bson.M{"value": bson.M{"$gt": 1}}}, Match(opt.Gt("value", 1)))
+}
@@ -0,0 +1,324 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidField is matched by errors.Is with FieldError
+var ErrInvalidField = errors.New("invalid field")
+
+// FieldError is a filter or sorting field that doesn't match model, it's returned in strict mode
+type FieldError struct {
+	Model  string
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %q of %s: %s", ErrInvalidField, e.Field, e.Model, e.Reason)
+}
+
+func (e *FieldError) Is(target error) bool {
+	return target == ErrInvalidField
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+	regexType    = reflect.TypeOf(primitive.Regex{})
+)
+
+// ValidateOptions checks that fields of filter and sorting exist in model and values
+// have field's type, rec is a model or pointer to slice of models
+func ValidateOptions(rec interface{}, opts ...opt.FnOpt) error {
+	t := reflect.TypeOf(rec)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return errIncorrectModelInterface
+	}
+
+	v := schemaValidator{model: t}
+	o := opt.New(opts...)
+	if o.SortBy != "" {
+		if _, err := v.resolve(o.SortBy); err != nil {
+			return err
+		}
+	}
+	return v.query(o.GetFilter(), "")
+}
+
+// validate checks that filter has no negated operators rejected by server
+// and checks options against model in strict mode
+func (w *dbWrapper) validate(rec interface{}, opts []opt.FnOpt) error {
+	if op, ok := negatedOperator(opt.GetFilter(opts...), false); ok {
+		return fmt.Errorf("%s can't be negated with $not or $nor", op)
+	}
+	if !w.strict {
+		return nil
+	}
+	return ValidateOptions(rec, opts...)
+}
+
+// negatedOperator returns $text, $near or $nearSphere found under $not or $nor
+func negatedOperator(query interface{}, negated bool) (string, bool) {
+	switch v := query.(type) {
+	case bson.M:
+		for key, val := range v {
+			switch key {
+			case "$text", "$near", "$nearSphere":
+				if negated {
+					return key, true
+				}
+			}
+			if op, ok := negatedOperator(val, negated || key == "$not" || key == "$nor"); ok {
+				return op, true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if op, ok := negatedOperator(item, negated); ok {
+				return op, true
+			}
+		}
+	case bson.A:
+		return negatedOperator([]interface{}(v), negated)
+	}
+	return "", false
+}
+
+type schemaValidator struct {
+	model reflect.Type
+}
+
+func (v schemaValidator) errorf(field, format string, args ...interface{}) error {
+	return &FieldError{Model: v.model.String(), Field: field, Reason: fmt.Sprintf(format, args...)}
+}
+
+// query validates filter document, prefix is a path of array for $elemMatch
+func (v schemaValidator) query(query bson.M, prefix string) error {
+	for key, val := range query {
+		switch key {
+		case "$and", "$or", "$nor":
+			items, ok := val.([]interface{})
+			if !ok {
+				if a, isA := val.(bson.A); isA {
+					items, ok = a, true
+				}
+			}
+			for _, item := range items {
+				if m, ok := item.(bson.M); ok {
+					if err := v.query(m, prefix); err != nil {
+						return err
+					}
+				}
+			}
+		case "$expr", "$text", "$where", "$comment":
+		default:
+			path := prefix + key
+			if strings.HasPrefix(key, "$") {
+				// operators on element itself in $elemMatch
+				path = strings.TrimSuffix(prefix, ".")
+				if err := v.operators(path, bson.M{key: val}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			ops, ok := val.(bson.M)
+			if !ok || !isOperators(ops) {
+				ops = bson.M{"$eq": val}
+			}
+			if err := v.operators(path, ops); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v schemaValidator) operators(path string, ops bson.M) error {
+	t, err := v.resolve(path)
+	if err != nil {
+		return err
+	}
+
+	for op, arg := range ops {
+		switch op {
+		case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+			if !compatible(t, arg) {
+				return v.errorf(path, "can't compare %s with %T", t, arg)
+			}
+		case "$in", "$nin", "$all":
+			items, ok := toInterfaces(arg)
+			if !ok {
+				return v.errorf(path, "%s needs array, got %T", op, arg)
+			}
+			for _, item := range items {
+				if !compatible(t, item) {
+					return v.errorf(path, "can't compare %s with %T", t, item)
+				}
+			}
+		case "$regex":
+			if k := elemType(t).Kind(); k != reflect.String && k != reflect.Interface {
+				return v.errorf(path, "can't match %s with regular expression", t)
+			}
+		case "$size", "$elemMatch":
+			if !isArray(t) && t.Kind() != reflect.Interface {
+				return v.errorf(path, "%s needs array field, got %s", op, t)
+			}
+			if m, ok := arg.(bson.M); ok && op == "$elemMatch" {
+				if err := v.query(m, path+"."); err != nil {
+					return err
+				}
+			}
+		case "$mod":
+			if !isNumber(elemType(t)) && t.Kind() != reflect.Interface {
+				return v.errorf(path, "$mod needs number field, got %s", t)
+			}
+		case "$not":
+			if m, ok := arg.(bson.M); ok {
+				if err := v.operators(path, m); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns type of field by dotted path, arrays are traversed by index or implicitly
+func (v schemaValidator) resolve(path string) (reflect.Type, error) {
+	t := v.model
+	for _, segment := range strings.Split(path, ".") {
+		t = deref(t)
+		if isArray(t) {
+			t = deref(t.Elem())
+			if _, err := strconv.Atoi(segment); err == nil {
+				continue
+			}
+		}
+
+		switch t.Kind() {
+		case reflect.Interface:
+			return t, nil
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			found := false
+			for _, f := range model.Fields(reflect.Zero(t).Interface()) {
+				if f.Name == segment {
+					t, found = f.Type, true
+					break
+				}
+			}
+			if !found {
+				return nil, v.errorf(path, "unknown field")
+			}
+		default:
+			return nil, v.errorf(path, "%s has no fields", t)
+		}
+	}
+	return deref(t), nil
+}
+
+// compatible responds whether value could be compared with field, value of element type
+// is accepted for arrays
+func compatible(t reflect.Type, val interface{}) bool {
+	if val == nil || t.Kind() == reflect.Interface {
+		return true
+	}
+	vt := reflect.TypeOf(val)
+	if vt.AssignableTo(t) || vt == regexType {
+		return true
+	}
+	if isArray(t) {
+		if compatible(deref(t.Elem()), val) {
+			return true
+		}
+		items, ok := toInterfaces(val)
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if !compatible(deref(t.Elem()), item) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case t == timeType:
+		return vt == timeType || vt == dateTimeType
+	case t == objectIDType:
+		return vt == objectIDType
+	case isNumber(t):
+		return isNumber(vt)
+	case t.Kind() == reflect.String, t.Kind() == reflect.Bool:
+		return vt.Kind() == t.Kind()
+	case t.Kind() == reflect.Struct, t.Kind() == reflect.Map:
+		return vt.Kind() == reflect.Map || vt == reflect.TypeOf(bson.D{})
+	}
+	return false
+}
+
+func toInterfaces(val interface{}) ([]interface{}, bool) {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	res := make([]interface{}, rv.Len())
+	for i := range res {
+		res[i] = rv.Index(i).Interface()
+	}
+	return res, true
+}
+
+func isOperators(m bson.M) bool {
+	for key := range m {
+		if !strings.HasPrefix(key, "$") {
+			return false
+		}
+	}
+	return len(m) > 0
+}
+
+func isArray(t reflect.Type) bool {
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	if isArray(t) {
+		return deref(t.Elem())
+	}
+	return t
+}
+
+func deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,82 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/sanches1984/gopkg-mongo-orm/model"
+	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+type schemaItem struct {
+	collection         struct{} `bson:"books"`
+	model.DefaultModel `bson:",inline"`
+	Name               string   `bson:"name"`
+	Value              int      `bson:"value"`
+	Tags               []string `bson:"tags"`
+	Author             struct {
+		Name string `bson:"name"`
+	} `bson:"author"`
+	Reviews []struct {
+		Rating float64 `bson:"rating"`
+	} `bson:"reviews"`
+	Location model.Point            `bson:"location"`
+	Extra    map[string]interface{} `bson:"extra"`
+}
+
+func TestValidateOptions(t *testing.T) {
+	valid := [][]opt.FnOpt{
+		opt.List(opt.Eq("name", "hello"), opt.Desc("createdAt")),
+		opt.List(opt.Gt("value", 1.5), opt.In("value", []int64{1, 2}), opt.IsNull("name")),
+		opt.List(opt.Eq("_id", primitive.NewObjectID()), opt.Lt("updatedAt", time.Now())),
+		opt.List(opt.Eq("tags", "a"), opt.All("tags", []string{"a", "b"}), opt.Size("tags", 2)),
+		opt.List(opt.Eq("author.name", "Tolstoy"), opt.Contains("author.name", "tol")),
+		opt.List(opt.Gt("reviews.rating", 4), opt.Ge("reviews.0.rating", 4)),
+		opt.List(opt.ElemMatch("reviews", opt.Gt("rating", 3), opt.Lt("rating", 5))),
+		opt.List(opt.ElemMatch("tags", opt.Regex("", "^a", ""))),
+		opt.List(opt.Or(opt.Eq("name", "a"), opt.Not(opt.Eq("value", 1)))),
+		opt.List(opt.Near("location", model.NewPoint(1, 2), 100, 0), opt.Eq("extra.any.path", 1)),
+		opt.List(opt.Text("hello", "", false), opt.Expr(bson.M{"$gt": bson.A{"$value", 1}})),
+	}
+	for _, opts := range valid {
+		require.NoError(t, ValidateOptions(&[]*schemaItem{}, opts...))
+	}
+
+	invalid := map[string][]opt.FnOpt{
+		`invalid field "nmae" of mongodb.schemaItem: unknown field`:                            opt.List(opt.Eq("nmae", "hello")),
+		`invalid field "created_at" of mongodb.schemaItem: unknown field`:                      opt.List(opt.Desc("created_at")),
+		`invalid field "author.title" of mongodb.schemaItem: unknown field`:                    opt.List(opt.Eq("author.title", "a")),
+		`invalid field "value" of mongodb.schemaItem: can't compare int with string`:           opt.List(opt.Eq("value", "1")),
+		`invalid field "name" of mongodb.schemaItem: can't compare string with int`:            opt.List(opt.Or(opt.Eq("name", 1))),
+		`invalid field "tags" of mongodb.schemaItem: can't compare []string with int`:          opt.List(opt.In("tags", []int{1})),
+		`invalid field "value" of mongodb.schemaItem: can't match int with regular expression`: opt.List(opt.Contains("value", "1")),
+		`invalid field "name" of mongodb.schemaItem: $size needs array field, got string`:      opt.List(opt.Size("name", 1)),
+		`invalid field "reviews.rating" of mongodb.schemaItem: can't compare float64 with string`: opt.List(
+			opt.ElemMatch("reviews", opt.Eq("rating", "good")),
+		),
+		`invalid field "value.x" of mongodb.schemaItem: int has no fields`: opt.List(opt.Eq("value.x", 1)),
+	}
+	for msg, opts := range invalid {
+		err := ValidateOptions(&schemaItem{}, opts...)
+		require.ErrorIs(t, err, ErrInvalidField)
+		require.EqualError(t, err, msg)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	w := newWrapper(false, WithStrictMode())
+	require.NoError(t, w.validate(&schemaItem{}, opt.List(opt.Eq("name", "hello"))))
+	require.ErrorIs(t, w.validate(&schemaItem{}, opt.List(opt.Eq("nmae", "hello"))), ErrInvalidField)
+
+	require.NoError(t, newWrapper(false).validate(&schemaItem{}, opt.List(opt.Eq("nmae", "hello"))))
+
+	mc, err := mongo.NewClient()
+	require.NoError(t, err)
+	w.db = mc.Database("test")
+	err = w.Aggregate(context.Background(), &[]*schemaItem{}, []bson.M{}, opt.List(opt.Eq("nmae", "hello")))
+	require.ErrorIs(t, err, ErrInvalidField)
+}
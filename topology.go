@@ -0,0 +1,66 @@
+package mongodb
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Topology is a kind of deployment client connected to
+type Topology int
+
+const (
+	TopologyUnknown Topology = iota
+	TopologyStandalone
+	TopologyReplicaSet
+	TopologySharded
+)
+
+func (t Topology) String() string {
+	switch t {
+	case TopologyStandalone:
+		return "standalone"
+	case TopologyReplicaSet:
+		return "replica set"
+	case TopologySharded:
+		return "sharded"
+	default:
+		return "unknown"
+	}
+}
+
+// SupportsTransactions responds whether transactions are available for topology
+func (t Topology) SupportsTransactions() bool {
+	return t == TopologyReplicaSet || t == TopologySharded
+}
+
+type helloResult struct {
+	SetName string `bson:"setName"`
+	Msg     string `bson:"msg"`
+}
+
+func (r helloResult) topology() Topology {
+	switch {
+	case r.Msg == "isdbgrid":
+		return TopologySharded
+	case r.SetName != "":
+		return TopologyReplicaSet
+	default:
+		return TopologyStandalone
+	}
+}
+
+// detectTopology asks server about deployment with hello command
+func detectTopology(ctx context.Context, client *mongo.Client) (Topology, error) {
+	var res helloResult
+	admin := client.Database("admin")
+	err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&res)
+	if err != nil {
+		// servers before 4.4.2 don't know hello command
+		if err = admin.RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&res); err != nil {
+			return TopologyUnknown, err
+		}
+	}
+
+	return res.topology(), nil
+}
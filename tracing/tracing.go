@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sanches1984/gopkg-mongo-orm/tracing"
+
+type hook struct {
+	tracer trace.Tracer
+}
+
+// NewHook returns hook that starts span per client's operation,
+// global tracer provider is used when tp is nil
+func NewHook(tp trace.TracerProvider) mongodb.Hook {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &hook{tracer: tp.Tracer(instrumentationName)}
+}
+
+func (h *hook) Before(ctx context.Context, op *mongodb.Operation) context.Context {
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemMongoDB,
+		semconv.DBNameKey.String(op.Database),
+		semconv.DBOperationKey.String(op.Name),
+	}
+	if op.Collection != "" {
+		attrs = append(attrs, semconv.DBMongoDBCollectionKey.String(op.Collection))
+	}
+	if op.Filter != nil {
+		// filter is already redacted by client, it's written as relaxed extended JSON
+		if statement, err := bson.MarshalExtJSON(op.Filter, false, false); err == nil {
+			attrs = append(attrs, semconv.DBStatementKey.String(string(statement)))
+		}
+	}
+
+	ctx, _ = h.tracer.Start(ctx, spanName(op), trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx
+}
+
+func (h *hook) After(ctx context.Context, op *mongodb.Operation, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func spanName(op *mongodb.Operation) string {
+	if op.Collection == "" {
+		return op.Name
+	}
+	return op.Collection + "." + op.Name
+}
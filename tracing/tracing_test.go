@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	mongodb "github.com/sanches1984/gopkg-mongo-orm"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestHook(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	h := NewHook(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	op := &mongodb.Operation{
+		Name:       "Find",
+		Database:   "test",
+		Collection: "books",
+		Filter:     bson.M{"name": bson.M{"$eq": "?"}},
+	}
+	ctx := h.Before(context.Background(), op)
+	h.After(ctx, op, errors.New("boom"))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "books.Find", spans[0].Name)
+	require.Equal(t, codes.Error, spans[0].Status.Code)
+	require.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("db.system", "mongodb"),
+		attribute.String("db.name", "test"),
+		attribute.String("db.operation", "Find"),
+		attribute.String("db.mongodb.collection", "books"),
+		attribute.String("db.statement", `{"name":{"$eq":"?"}}`),
+	}, spans[0].Attributes)
+}
@@ -2,25 +2,277 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"reflect"
+	"time"
 )
 
-type transactionFunc func(session mongo.Session, sc mongo.SessionContext) error
+const (
+	defaultTxMaxDuration = 120 * time.Second
+	defaultTxBackoff     = 10 * time.Millisecond
 
-func transaction(ctx context.Context, client *mongo.Client, f transactionFunc) error {
-	session, err := client.StartSession()
-	if err != nil {
-		return err
+	labelTransientTransaction = "TransientTransactionError"
+	labelUnknownCommitResult  = "UnknownTransactionCommitResult"
+	codeMaxTimeMSExpired      = 50
+)
+
+// NestedTx defines behaviour of transaction started inside another one
+type NestedTx int
+
+const (
+	// NestedJoin runs nested function in outer transaction
+	NestedJoin NestedTx = iota
+	// NestedFail returns error on nested transaction
+	NestedFail
+)
+
+// TxOptions is a transaction options
+type TxOptions struct {
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+	MaxCommitTime  time.Duration
+	Nested         NestedTx
+}
+
+func (o *TxOptions) transactionOptions() *options.TransactionOptions {
+	opts := options.Transaction()
+	if o == nil {
+		return opts
+	}
+
+	if o.ReadConcern != nil {
+		opts.SetReadConcern(o.ReadConcern)
+	}
+	if o.WriteConcern != nil {
+		opts.SetWriteConcern(o.WriteConcern)
+	}
+	if o.ReadPreference != nil {
+		opts.SetReadPreference(o.ReadPreference)
+	}
+	if o.MaxCommitTime > 0 {
+		opts.SetMaxCommitTime(&o.MaxCommitTime)
+	}
+	return opts
+}
+
+// joinable responds whether nested call with options can join transaction started with outer options,
+// nested options without concerns, read preference and commit time are always joinable
+func (o *TxOptions) joinable(outer *options.TransactionOptions) bool {
+	opts := o.transactionOptions()
+	if reflect.DeepEqual(opts, options.Transaction()) {
+		return true
+	}
+	if outer == nil {
+		outer = options.Transaction()
+	}
+	return reflect.DeepEqual(opts, outer)
+}
+
+func (o *TxOptions) nested() NestedTx {
+	if o == nil {
+		return NestedJoin
+	}
+	return o.Nested
+}
+
+type txKey struct{}
+
+// txState is a transaction stored in context
+type txState struct {
+	// opts are options transaction is started with
+	opts          *options.TransactionOptions
+	afterCommit   []func(context.Context)
+	afterRollback []func(context.Context)
+}
+
+// InTransaction responds whether context belongs to transaction
+func InTransaction(ctx context.Context) bool {
+	return txFromContext(ctx) != nil
+}
+
+// NewTxContext returns context of new transaction for IClient implementations,
+// returned function must be called when transaction ends to run callbacks
+func NewTxContext(ctx context.Context) (context.Context, func(committed bool)) {
+	tx := &txState{}
+	return withTx(ctx, tx), func(committed bool) {
+		if committed {
+			runCallbacks(ctx, tx.afterCommit)
+		} else {
+			runCallbacks(ctx, tx.afterRollback)
+		}
 	}
-	defer session.EndSession(ctx)
+}
+
+// AfterCommit registers callback that runs after transaction in context is committed,
+// without transaction callback runs immediately
+func AfterCommit(ctx context.Context, fn func(context.Context)) {
+	if tx := txFromContext(ctx); tx != nil {
+		tx.afterCommit = append(tx.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
 
-	if err = session.StartTransaction(); err != nil {
+// AfterRollback registers callback that runs after transaction in context is rolled back,
+// without transaction callback runs immediately
+func AfterRollback(ctx context.Context, fn func(context.Context)) {
+	if tx := txFromContext(ctx); tx != nil {
+		tx.afterRollback = append(tx.afterRollback, fn)
+		return
+	}
+	fn(ctx)
+}
+
+func runCallbacks(ctx context.Context, callbacks []func(context.Context)) {
+	for _, fn := range callbacks {
+		fn(ctx)
+	}
+}
+
+func withTx(ctx context.Context, tx *txState) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) *txState {
+	tx, _ := ctx.Value(txKey{}).(*txState)
+	return tx
+}
+
+// txSession is a part of mongo.Session used by transactions
+type txSession interface {
+	StartTransaction(opts ...*options.TransactionOptions) error
+	AbortTransaction(ctx context.Context) error
+	CommitTransaction(ctx context.Context) error
+	// Context binds session to context
+	Context(ctx context.Context) context.Context
+}
+
+type mongoSession struct {
+	mongo.Session
+}
+
+func (s mongoSession) Context(ctx context.Context) context.Context {
+	return mongo.NewSessionContext(ctx, s.Session)
+}
+
+// txRetry is a retry policy for transient transaction errors
+type txRetry struct {
+	maxDuration time.Duration
+	backoff     time.Duration
+}
+
+// transaction runs fn in transaction, whole transaction is retried on TransientTransactionError
+// and commit is retried on UnknownTransactionCommitResult while max duration is not exceeded
+func transaction(ctx context.Context, session txSession, txOpts *options.TransactionOptions, retry txRetry, log logger.Logger, fn func(context.Context) error) error {
+	start := time.Now()
+	sessCtx := session.Context(ctx)
+
+	for {
+		if err := session.StartTransaction(txOpts); err != nil {
+			return err
+		}
+
+		tx := &txState{opts: txOpts}
+		sc := withTx(sessCtx, tx)
+		if err := fn(sc); err != nil {
+			if rollbackErr := session.AbortTransaction(sc); rollbackErr != nil {
+				log.Error("failed to rollback transaction", rollbackErr)
+			}
+			runCallbacks(ctx, tx.afterRollback)
+
+			if hasErrorLabel(err, labelTransientTransaction) && retry.allowed(start) {
+				log.Warn("retry transaction", "error", err)
+				if err := retry.wait(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		err := commit(sc, session, retry, log, start)
+		if err == nil {
+			runCallbacks(ctx, tx.afterCommit)
+			return nil
+		}
+
+		// commit could succeed when its result is unknown, so no callbacks are run
+		if !commitAborted(err) {
+			return err
+		}
+		runCallbacks(ctx, tx.afterRollback)
+		if hasErrorLabel(err, labelTransientTransaction) && retry.allowed(start) {
+			log.Warn("retry transaction", "error", err)
+			if err := retry.wait(ctx); err != nil {
+				return err
+			}
+			continue
+		}
 		return err
 	}
+}
+
+func commit(ctx context.Context, session txSession, retry txRetry, log logger.Logger, start time.Time) error {
+	for {
+		err := session.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
 
-	wrapperFn := func(sc mongo.SessionContext) error {
-		return f(session, sc)
+		if !hasErrorLabel(err, labelUnknownCommitResult) || isMaxTimeExpired(err) || !retry.allowed(start) {
+			return err
+		}
+		log.Warn("retry transaction commit", "error", err)
+		if err := retry.wait(ctx); err != nil {
+			return err
+		}
 	}
+}
+
+// commitAborted responds whether failed commit is known to abort transaction: transient error
+// or server error other than unknown commit result, network errors and timeouts
+func commitAborted(err error) bool {
+	if hasErrorLabel(err, labelTransientTransaction) {
+		return true
+	}
+	if hasErrorLabel(err, labelUnknownCommitResult) || mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return false
+	}
+	var se mongo.ServerError
+	return errors.As(err, &se)
+}
+
+func (r txRetry) allowed(start time.Time) bool {
+	return time.Since(start) < r.maxDuration
+}
+
+func (r txRetry) wait(ctx context.Context) error {
+	if r.backoff <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(r.backoff)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorLabel(label)
+}
 
-	return mongo.WithSession(ctx, session, wrapperFn)
+func isMaxTimeExpired(err error) bool {
+	return hasErrorCode(err, codeMaxTimeMSExpired)
 }
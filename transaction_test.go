@@ -0,0 +1,253 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"testing"
+	"time"
+)
+
+type fakeSession struct {
+	started   int
+	aborted   int
+	committed int
+	commitErr []error
+}
+
+func (s *fakeSession) StartTransaction(...*options.TransactionOptions) error {
+	s.started++
+	return nil
+}
+
+func (s *fakeSession) AbortTransaction(context.Context) error {
+	s.aborted++
+	return nil
+}
+
+func (s *fakeSession) CommitTransaction(context.Context) error {
+	s.committed++
+	if len(s.commitErr) > 0 {
+		err := s.commitErr[0]
+		s.commitErr = s.commitErr[1:]
+		return err
+	}
+	return nil
+}
+
+func (s *fakeSession) Context(ctx context.Context) context.Context {
+	return ctx
+}
+
+func labeledError(label string) error {
+	return mongo.CommandError{Code: 112, Message: "write conflict", Labels: []string{label}}
+}
+
+var testRetry = txRetry{maxDuration: time.Second}
+
+func TestTransaction_Commit(t *testing.T) {
+	s := &fakeSession{}
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, s.started)
+	require.Equal(t, 1, s.committed)
+	require.Equal(t, 0, s.aborted)
+}
+
+func TestTransaction_Rollback(t *testing.T) {
+	s := &fakeSession{}
+	fnErr := errors.New("rollback")
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		return fnErr
+	})
+	require.ErrorIs(t, err, fnErr)
+	require.Equal(t, 1, s.started)
+	require.Equal(t, 0, s.committed)
+	require.Equal(t, 1, s.aborted)
+}
+
+func TestTransaction_RetryTransient(t *testing.T) {
+	s := &fakeSession{}
+	calls := 0
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return labeledError(labelTransientTransaction)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+	require.Equal(t, 3, s.started)
+	require.Equal(t, 2, s.aborted)
+	require.Equal(t, 1, s.committed)
+}
+
+func TestTransaction_RetryCommit(t *testing.T) {
+	s := &fakeSession{commitErr: []error{
+		labeledError(labelUnknownCommitResult),
+		labeledError(labelUnknownCommitResult),
+	}}
+	calls := 0
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+	require.Equal(t, 3, s.committed)
+}
+
+func TestTransaction_RetryTransientCommit(t *testing.T) {
+	s := &fakeSession{commitErr: []error{labeledError(labelTransientTransaction)}}
+	calls := 0
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Equal(t, 2, s.committed)
+}
+
+func TestTransaction_NoRetry(t *testing.T) {
+	s := &fakeSession{}
+	calls := 0
+	err := transaction(context.Background(), s, nil, txRetry{}, logger.Nop(), func(context.Context) error {
+		calls++
+		return labeledError(labelTransientTransaction)
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, calls)
+	require.True(t, hasErrorLabel(err, labelTransientTransaction))
+}
+
+func TestTransaction_CommitMaxTimeExpired(t *testing.T) {
+	commitErr := mongo.CommandError{Code: codeMaxTimeMSExpired, Labels: []string{labelUnknownCommitResult}}
+	s := &fakeSession{commitErr: []error{commitErr}}
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(context.Context) error {
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, 1, s.committed)
+}
+
+func TestTransaction_Nested(t *testing.T) {
+	w := &dbWrapper{}
+	s := &fakeSession{}
+	err := transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(ctx context.Context) error {
+		require.NotNil(t, txFromContext(ctx))
+
+		joined := false
+		err := w.WithTX(ctx, func(context.Context) error {
+			joined = true
+			return nil
+		})
+		require.NoError(t, err)
+		require.True(t, joined)
+
+		return w.WithTXOptions(ctx, &TxOptions{Nested: NestedFail}, func(context.Context) error {
+			return nil
+		})
+	})
+	require.ErrorIs(t, err, errNestedTransaction)
+	require.Equal(t, 1, s.started)
+	require.Equal(t, 1, s.aborted)
+}
+
+func TestTransaction_NestedOptions(t *testing.T) {
+	w := &dbWrapper{}
+	outer := &TxOptions{ReadConcern: readconcern.Snapshot()}
+	err := transaction(context.Background(), &fakeSession{}, outer.transactionOptions(), testRetry, logger.Nop(), func(ctx context.Context) error {
+		joins := []*TxOptions{nil, {Nested: NestedJoin}, {ReadConcern: readconcern.Snapshot()}}
+		for _, o := range joins {
+			require.NoError(t, w.WithTXOptions(ctx, o, func(context.Context) error { return nil }))
+		}
+
+		return w.WithTXOptions(ctx, &TxOptions{ReadConcern: readconcern.Majority()}, func(context.Context) error {
+			return nil
+		})
+	})
+	require.ErrorIs(t, err, errNestedTxOptions)
+}
+
+func TestTxOptions(t *testing.T) {
+	var o *TxOptions
+	require.Equal(t, NestedJoin, o.nested())
+	require.Nil(t, o.transactionOptions().MaxCommitTime)
+
+	o = &TxOptions{
+		ReadConcern:    readconcern.Snapshot(),
+		WriteConcern:   writeconcern.New(writeconcern.WMajority()),
+		ReadPreference: readpref.Primary(),
+		MaxCommitTime:  time.Second,
+	}
+	opts := o.transactionOptions()
+	require.Equal(t, readconcern.Snapshot(), opts.ReadConcern)
+	require.Equal(t, o.WriteConcern, opts.WriteConcern)
+	require.Equal(t, readpref.Primary(), opts.ReadPreference)
+	require.Equal(t, time.Second, *opts.MaxCommitTime)
+}
+
+func TestTransaction_Callbacks(t *testing.T) {
+	var events []string
+	register := func(ctx context.Context, name string) {
+		AfterCommit(ctx, func(context.Context) { events = append(events, name+" commit") })
+		AfterRollback(ctx, func(context.Context) { events = append(events, name+" rollback") })
+	}
+
+	register(context.Background(), "no tx")
+	require.Equal(t, []string{"no tx commit", "no tx rollback"}, events)
+
+	events = nil
+	calls := 0
+	err := transaction(context.Background(), &fakeSession{}, nil, testRetry, logger.Nop(), func(ctx context.Context) error {
+		calls++
+		register(ctx, "tx")
+		if calls == 1 {
+			require.Empty(t, events)
+			return labeledError(labelTransientTransaction)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"tx rollback", "tx commit"}, events)
+
+	events = nil
+	s := &fakeSession{commitErr: []error{mongo.CommandError{Code: 251, Message: "no such transaction"}}}
+	err = transaction(context.Background(), s, nil, testRetry, logger.Nop(), func(ctx context.Context) error {
+		register(ctx, "tx")
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"tx rollback"}, events)
+}
+
+func TestTransaction_CallbacksUnknownCommitResult(t *testing.T) {
+	commitErrors := []error{
+		labeledError(labelUnknownCommitResult),
+		mongo.CommandError{Code: 89, Message: "network timeout", Labels: []string{"NetworkError"}},
+		errors.New("connection closed"),
+	}
+
+	for _, commitErr := range commitErrors {
+		var events []string
+		s := &fakeSession{commitErr: []error{commitErr}}
+		err := transaction(context.Background(), s, nil, txRetry{}, logger.Nop(), func(ctx context.Context) error {
+			AfterCommit(ctx, func(context.Context) { events = append(events, "commit") })
+			AfterRollback(ctx, func(context.Context) { events = append(events, "rollback") })
+			return nil
+		})
+		require.Equal(t, commitErr, err)
+		require.Equal(t, 1, s.committed)
+		require.Empty(t, events)
+	}
+}
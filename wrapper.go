@@ -3,64 +3,130 @@ package mongodb
 import (
 	"context"
 	"errors"
-	"github.com/rs/zerolog/log"
+	"github.com/sanches1984/gopkg-mongo-orm/logger"
 	"github.com/sanches1984/gopkg-mongo-orm/model"
 	"github.com/sanches1984/gopkg-mongo-orm/repository/opt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"reflect"
+	"time"
 )
 
-const fieldCollection = "collection"
+const (
+	fieldCollection = "collection"
+	tagDatabase     = "database"
+)
 
 var errIncorrectModelInterface = errors.New("incorrect model interface")
-var errNoReplicaSet = errors.New("use replica set for transactions")
+var errNoTransactions = errors.New("transactions need replica set or sharded cluster")
+var errNestedTransaction = errors.New("transaction already started")
+var errNestedTxOptions = errors.New("nested transaction options differ from outer transaction ones")
+var errCountNear = errors.New("$near and $nearSphere are not supported by Count, use GeoWithin with CenterSphere")
 
 type dbWrapper struct {
-	db    *mongo.Database
-	hasRS bool
+	db        *mongo.Database
+	topology  Topology
+	txEnabled *bool
+	ownClient bool
+	txRetry   txRetry
+	logger    logger.Logger
+	hooks     []Hook
+	strict    bool
+	collScan  *collScanGuard
+
+	clientOpts []*options.ClientOptions
 }
 
 func (w *dbWrapper) DB() *mongo.Database {
 	return w.db
 }
 
+// WithDatabase returns client bound to another database on the same connection,
+// its Close doesn't disconnect shared connection
+func (w *dbWrapper) WithDatabase(name string) IClient {
+	c := *w
+	c.db = w.db.Client().Database(name)
+	c.ownClient = false
+	return &c
+}
+
+func (w *dbWrapper) Topology() Topology {
+	return w.topology
+}
+
 func (w *dbWrapper) Ping(ctx context.Context) error {
 	return w.db.Client().Ping(ctx, nil)
 }
 
 func (w *dbWrapper) Close() error {
-	return w.db.Client().Disconnect(context.Background())
+	if !w.ownClient {
+		return nil
+	}
+	if err := w.db.Client().Disconnect(context.Background()); err != nil {
+		return err
+	}
+	w.logger.Info("disconnected from database", "database", w.db.Name())
+	return nil
 }
 
-// WithTX run in transaction (need replica set!)
+// WithTX run in transaction (need replica set or sharded cluster!), nested call joins outer transaction
 func (w *dbWrapper) WithTX(ctx context.Context, fn func(context.Context) error) error {
-	if !w.hasRS {
-		return errNoReplicaSet
-	}
-	return transaction(ctx, w.db.Client(), func(session mongo.Session, sc mongo.SessionContext) error {
-		if err := fn(sc); err != nil {
-			rollbackErr := session.AbortTransaction(sc)
-			if rollbackErr != nil {
-				// todo get logger from context
-				log.Error().Err(rollbackErr).Msg("failed to rollback transaction")
-			}
-			return err
+	return w.WithTXOptions(ctx, nil, fn)
+}
+
+// WithTXOptions run in transaction with options, nested call joining outer transaction
+// fails when it sets options different from outer ones
+func (w *dbWrapper) WithTXOptions(ctx context.Context, txOpts *TxOptions, fn func(context.Context) error) (err error) {
+	if tx := txFromContext(ctx); tx != nil {
+		if txOpts.nested() == NestedFail {
+			return errNestedTransaction
 		}
+		if !txOpts.joinable(tx.opts) {
+			return errNestedTxOptions
+		}
+		return fn(ctx)
+	}
+
+	if !w.supportsTransactions() {
+		return errNoTransactions
+	}
+
+	ctx, end := w.startOperation(ctx, "WithTX", nil, nil, nil)
+	defer func() { err = end(err) }()
+
+	session, err := w.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	return transaction(ctx, mongoSession{session}, txOpts.transactionOptions(), w.txRetry, w.log(ctx), fn)
+}
 
-		return session.CommitTransaction(sc)
-	})
+// log returns logger from context or client's one
+func (w *dbWrapper) log(ctx context.Context) logger.Logger {
+	return logger.FromContext(ctx, w.logger)
 }
 
-func (w *dbWrapper) Create(ctx context.Context, rec interface{}) error {
-	coll, elem, err := getCollectionAndModel(rec)
+func (w *dbWrapper) supportsTransactions() bool {
+	if w.txEnabled != nil {
+		return *w.txEnabled
+	}
+	return w.topology.SupportsTransactions()
+}
+
+func (w *dbWrapper) Create(ctx context.Context, rec interface{}) (err error) {
+	coll, elem, err := w.getCollectionAndModel(rec)
 	if err != nil {
 		return err
 	}
 
+	ctx, end := w.startOperation(ctx, "Create", coll, rec, nil)
+	defer func() { err = end(err) }()
+
 	elem.Creating()
-	res, err := w.db.Collection(coll).InsertOne(ctx, rec)
+	res, err := coll.InsertOne(ctx, rec)
 	if err != nil {
 		return err
 	}
@@ -69,30 +135,38 @@ func (w *dbWrapper) Create(ctx context.Context, rec interface{}) error {
 	return nil
 }
 
-func (w *dbWrapper) Update(ctx context.Context, rec interface{}) error {
-	coll, elem, err := getCollectionAndModel(rec)
+func (w *dbWrapper) Update(ctx context.Context, rec interface{}) (err error) {
+	coll, elem, err := w.getCollectionAndModel(rec)
 	if err != nil {
 		return err
 	}
 
+	filter := bson.M{"_id": elem.GetID()}
+	ctx, end := w.startOperation(ctx, "Update", coll, rec, filter)
+	defer func() { err = end(err) }()
+
 	elem.Updating()
-	_, err = w.db.Collection(coll).UpdateOne(ctx, bson.M{"_id": elem.GetID()}, bson.M{"$set": elem})
+	_, err = coll.UpdateOne(ctx, filter, bson.M{"$set": elem})
 	return err
 }
 
-func (w *dbWrapper) Upsert(ctx context.Context, rec interface{}) error {
-	coll, elem, err := getCollectionAndModel(rec)
+func (w *dbWrapper) Upsert(ctx context.Context, rec interface{}) (err error) {
+	coll, elem, err := w.getCollectionAndModel(rec)
 	if err != nil {
 		return err
 	}
 
+	filter := bson.M{"_id": elem.GetID()}
+	ctx, end := w.startOperation(ctx, "Upsert", coll, rec, filter)
+	defer func() { err = end(err) }()
+
 	if elem.IsNew() {
 		elem.Creating()
 	} else {
 		elem.Updating()
 	}
 
-	res, err := w.db.Collection(coll).UpdateOne(ctx, bson.M{"_id": elem.GetID()}, bson.M{"$set": elem}, options.Update().SetUpsert(true))
+	res, err := coll.UpdateOne(ctx, filter, bson.M{"$set": elem}, options.Update().SetUpsert(true))
 	if err != nil {
 		return err
 	}
@@ -101,13 +175,25 @@ func (w *dbWrapper) Upsert(ctx context.Context, rec interface{}) error {
 	return nil
 }
 
-func (w *dbWrapper) UpdateWhere(ctx context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
-	coll, err := getCollection(rec)
+func (w *dbWrapper) UpdateWhere(ctx context.Context, rec interface{}, opts []opt.FnOpt) (n int64, err error) {
+	coll, err := w.getCollection(rec)
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := w.db.Collection(coll).UpdateMany(ctx, opt.GetFilter(opts...), nil, options.Update().SetUpsert(true))
+	if err := w.validate(rec, opts); err != nil {
+		return 0, err
+	}
+
+	o := opt.New(opts...)
+	filter := o.GetFilter()
+	ctx, end := w.startOperation(ctx, "UpdateWhere", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	ctx, cancel := withMaxTime(ctx, o.MaxTime)
+	defer cancel()
+
+	res, err := coll.UpdateMany(ctx, filter, nil, o.GetUpdateOptions().SetUpsert(true))
 	if err != nil {
 		return 0, err
 	}
@@ -115,23 +201,39 @@ func (w *dbWrapper) UpdateWhere(ctx context.Context, rec interface{}, opts []opt
 	return res.ModifiedCount, nil
 }
 
-func (w *dbWrapper) Delete(ctx context.Context, rec interface{}) error {
-	coll, elem, err := getCollectionAndModel(rec)
+func (w *dbWrapper) Delete(ctx context.Context, rec interface{}) (err error) {
+	coll, elem, err := w.getCollectionAndModel(rec)
 	if err != nil {
 		return err
 	}
 
-	_, err = w.db.Collection(coll).DeleteOne(ctx, bson.M{"_id": elem.GetID()})
+	filter := bson.M{"_id": elem.GetID()}
+	ctx, end := w.startOperation(ctx, "Delete", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	_, err = coll.DeleteOne(ctx, filter)
 	return err
 }
 
-func (w *dbWrapper) DeleteWhere(ctx context.Context, rec interface{}, opts []opt.FnOpt) (int64, error) {
-	coll, err := getCollection(rec)
+func (w *dbWrapper) DeleteWhere(ctx context.Context, rec interface{}, opts []opt.FnOpt) (n int64, err error) {
+	coll, err := w.getCollection(rec)
 	if err != nil {
 		return 0, err
 	}
 
-	res, err := w.db.Collection(coll).DeleteMany(ctx, opt.GetFilter(opts...))
+	if err := w.validate(rec, opts); err != nil {
+		return 0, err
+	}
+
+	o := opt.New(opts...)
+	filter := o.GetFilter()
+	ctx, end := w.startOperation(ctx, "DeleteWhere", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	ctx, cancel := withMaxTime(ctx, o.MaxTime)
+	defer cancel()
+
+	res, err := coll.DeleteMany(ctx, filter, o.GetDeleteOptions())
 	if err != nil {
 		return 0, err
 	}
@@ -139,71 +241,227 @@ func (w *dbWrapper) DeleteWhere(ctx context.Context, rec interface{}, opts []opt
 	return res.DeletedCount, nil
 }
 
-func (w *dbWrapper) FindByID(ctx context.Context, rec interface{}) error {
-	coll, elem, err := getCollectionAndModel(rec)
+func (w *dbWrapper) FindByID(ctx context.Context, rec interface{}) (err error) {
+	coll, elem, err := w.getCollectionAndModel(rec)
 	if err != nil {
 		return err
 	}
 
-	res := w.db.Collection(coll).FindOne(ctx, bson.M{"_id": elem.GetID()})
+	filter := bson.M{"_id": elem.GetID()}
+	ctx, end := w.startOperation(ctx, "FindByID", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	return coll.FindOne(ctx, filter).Decode(rec)
+}
+
+func (w *dbWrapper) Find(ctx context.Context, rec interface{}, opts []opt.FnOpt) (err error) {
+	coll, err := w.getCollectionFromSlice(rec)
+	if err != nil {
+		return err
+	}
+
+	if err := w.validate(rec, opts); err != nil {
+		return err
+	}
+
+	o := opt.New(opts...)
+	if err := w.checkCollScan(ctx, coll, rec, o); err != nil {
+		return err
+	}
+
+	filter := o.GetFilter()
+	ctx, end := w.startOperation(ctx, "Find", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	res, err := coll.Find(ctx, filter, o.GetOptions())
+	if err != nil {
+		return err
+	}
+	return res.All(ctx, rec)
+}
+
+// FindOne finds first document matching options, ErrNotFound is returned when there is no one
+func (w *dbWrapper) FindOne(ctx context.Context, rec interface{}, opts []opt.FnOpt) (err error) {
+	coll, err := w.getCollection(rec)
 	if err != nil {
 		return err
 	}
 
-	return res.Decode(rec)
+	if err := w.validate(rec, opts); err != nil {
+		return err
+	}
+
+	o := opt.New(opts...)
+	if err := w.checkCollScan(ctx, coll, rec, o); err != nil {
+		return err
+	}
+
+	filter := o.GetFilter()
+	ctx, end := w.startOperation(ctx, "FindOne", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	return coll.FindOne(ctx, filter, o.GetFindOneOptions()).Decode(rec)
+}
+
+// Count returns number of documents matching options, paging is ignored,
+// $near and $nearSphere are not supported, use opt.GeoWithin with opt.CenterSphere instead
+func (w *dbWrapper) Count(ctx context.Context, rec interface{}, opts []opt.FnOpt) (n int64, err error) {
+	coll, err := w.getCollection(rec)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := w.validate(rec, opts); err != nil {
+		return 0, err
+	}
+
+	o := opt.New(opts...)
+	filter := o.GetFilter()
+	if filter == nil {
+		filter = bson.M{}
+	}
+	if hasOperator(filter, "$near", "$nearSphere") {
+		return 0, errCountNear
+	}
+
+	if err := w.checkCollScan(ctx, coll, rec, o); err != nil {
+		return 0, err
+	}
+
+	ctx, end := w.startOperation(ctx, "Count", coll, rec, filter)
+	defer func() { err = end(err) }()
+
+	return coll.CountDocuments(ctx, filter, o.GetCountOptions())
 }
 
-func (w *dbWrapper) Find(ctx context.Context, rec interface{}, opts []opt.FnOpt) error {
-	coll, err := getCollectionFromSlice(rec)
+// Aggregate runs pipeline on collection of models and decodes results to pointer to slice of models,
+// only query options like opt.Hint or opt.AllowDiskUse are applied, filter should be set by stage.Match
+func (w *dbWrapper) Aggregate(ctx context.Context, rec interface{}, pipeline interface{}, opts []opt.FnOpt) (err error) {
+	coll, err := w.getCollectionFromSlice(rec)
 	if err != nil {
 		return err
 	}
 
-	res, err := w.db.Collection(coll).Find(ctx, opt.GetFilter(opts...), opt.GetOptions(opts...))
+	if err := w.validate(rec, opts); err != nil {
+		return err
+	}
+
+	ctx, end := w.startOperation(ctx, "Aggregate", coll, rec, nil)
+	defer func() { err = end(err) }()
+
+	res, err := coll.Aggregate(ctx, pipeline, opt.New(opts...).GetAggregateOptions())
 	if err != nil {
 		return err
 	}
 	return res.All(ctx, rec)
 }
 
-func getCollectionFromSlice(arr interface{}) (string, error) {
+// hasOperator responds whether query has any of operators at any level
+func hasOperator(query interface{}, ops ...string) bool {
+	switch v := query.(type) {
+	case bson.M:
+		for key, val := range v {
+			for _, op := range ops {
+				if key == op {
+					return true
+				}
+			}
+			if hasOperator(val, ops...) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasOperator(item, ops...) {
+				return true
+			}
+		}
+	case bson.A:
+		return hasOperator([]interface{}(v), ops...)
+	}
+	return false
+}
+
+// withMaxTime limits context by max time of operation that has no server side limit
+func withMaxTime(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+func (w *dbWrapper) getCollectionFromSlice(arr interface{}) (*mongo.Collection, error) {
 	v := reflect.ValueOf(arr).Elem()
 	if v.Kind() != reflect.Slice {
-		return "", errIncorrectModelInterface
+		return nil, errIncorrectModelInterface
 	}
 
 	obj := reflect.New(v.Type().Elem()).Elem().Interface()
-	return getCollection(obj)
+	return w.getCollection(obj)
 }
 
-func getCollection(item interface{}) (string, error) {
-	_, ok := item.(model.Model)
-	if !ok {
-		return "", errIncorrectModelInterface
+func (w *dbWrapper) getCollection(item interface{}) (*mongo.Collection, error) {
+	if _, ok := item.(model.Model); !ok {
+		return nil, errIncorrectModelInterface
 	}
 
-	if field, ok := reflect.TypeOf(item).Elem().FieldByName(fieldCollection); ok {
-		collName := field.Tag.Get("bson")
-		if collName != "" {
-			return collName, nil
-		}
+	collName, dbName, err := getCollectionName(item)
+	if err != nil {
+		return nil, err
 	}
 
-	return "", errIncorrectModelInterface
+	return w.database(dbName).Collection(collName), nil
 }
 
-func getCollectionAndModel(item interface{}) (string, model.Model, error) {
+func (w *dbWrapper) getCollectionAndModel(item interface{}) (*mongo.Collection, model.Model, error) {
 	v, ok := item.(model.Model)
 	if !ok {
-		return "", nil, errIncorrectModelInterface
+		return nil, nil, errIncorrectModelInterface
+	}
+
+	collName, dbName, err := getCollectionName(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return w.database(dbName).Collection(collName), v, nil
+}
+
+// database returns model's database or default one
+func (w *dbWrapper) database(name string) *mongo.Database {
+	if name == "" || name == w.db.Name() {
+		return w.db
+	}
+	return w.db.Client().Database(name)
+}
+
+// CollectionName returns collection and database names of model or pointer to slice of models,
+// database is empty when model doesn't set it
+func CollectionName(rec interface{}) (string, string, error) {
+	v := reflect.ValueOf(rec)
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Slice {
+		rec = reflect.New(v.Elem().Type().Elem()).Elem().Interface()
+	}
+
+	if _, ok := rec.(model.Model); !ok {
+		return "", "", errIncorrectModelInterface
+	}
+	return getCollectionName(rec)
+}
+
+// getCollectionName returns collection and database names from model's `collection` field tags
+func getCollectionName(item interface{}) (string, string, error) {
+	t := reflect.TypeOf(item)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
 
-	if field, ok := reflect.TypeOf(item).Elem().FieldByName(fieldCollection); ok {
+	if field, ok := t.FieldByName(fieldCollection); ok {
 		collName := field.Tag.Get("bson")
 		if collName != "" {
-			return collName, v, nil
+			return collName, field.Tag.Get(tagDatabase), nil
 		}
 	}
 
-	return "", nil, errIncorrectModelInterface
+	return "", "", errIncorrectModelInterface
 }
@@ -53,7 +53,7 @@ func TestCRUD(t *testing.T) {
 	arr := []*testItem{}
 	err = client.Find(ctx, &arr, opt.List(
 		opt.Eq("name", "hello"),
-		opt.Desc("created_at"),
+		opt.Desc("createdAt"),
 		opt.Paging(1, 10),
 	))
 	require.NoError(t, err)
@@ -88,6 +88,21 @@ func TestTransaction(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestWithDatabase_Close(t *testing.T) {
+	ctx := context.Background()
+	client, err := Connect("test", getConfig())
+	require.NoError(t, err)
+	defer client.Close()
+
+	archive := client.WithDatabase("archive")
+	require.NoError(t, archive.Close())
+
+	require.NoError(t, client.Ping(ctx))
+	require.NoError(t, archive.Ping(ctx))
+	arr := []*testItem{}
+	require.NoError(t, client.Find(ctx, &arr, nil))
+}
+
 func getConfig() *Config {
 	if err := godotenv.Load(); err != nil {
 		fmt.Println(".env file not found")